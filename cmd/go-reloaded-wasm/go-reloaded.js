@@ -0,0 +1,35 @@
+// Loader for the WebAssembly build of go-reloaded. Load wasm_exec.js from
+// the Go distribution first, so that globalThis.Go is defined, then:
+//
+//   const reloaded = await load(bytes);   // bytes of go-reloaded.wasm
+//   reloaded.process("it was a apple (up, 2)", "fsm", { scope: "line" });
+//
+// process and explain throw an Error for an invalid mode or options.
+"use strict";
+
+async function load(bytes) {
+  const go = new globalThis.Go();
+  const { instance } = await WebAssembly.instantiate(bytes, go.importObject);
+  // main sets globalThis.goReloaded before it blocks, so the API is ready
+  // once run has started
+  go.run(instance);
+  const api = globalThis.goReloaded;
+
+  const check = (result) => {
+    if (result.error !== undefined) {
+      throw new Error(result.error);
+    }
+    return result;
+  };
+  return {
+    process: (text, mode = "pipeline", options = {}) => check(api.process(text, mode, options)),
+    explain: (text, mode = "pipeline", options = {}) => check(api.explain(text, mode, options)),
+    markers: () => api.markers(),
+  };
+}
+
+if (typeof module !== "undefined") {
+  module.exports = { load };
+} else {
+  globalThis.goReloadedLoad = load;
+}
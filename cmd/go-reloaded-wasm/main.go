@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+// Command go-reloaded-wasm is the WebAssembly build of the processors. It
+// sets globalThis.goReloaded to an object with these functions:
+//
+//	process(text, mode, options)  {output, diagnostics}
+//	explain(text, mode, options)  {output, steps, diagnostics}
+//	markers()                     [{name, description, takesCount}]
+//
+// mode defaults to "pipeline" and options may set scope, whitespace,
+// locale, unknown-markers, rules and markers as in a request to the HTTP
+// API. Errors are returned as {error}; go-reloaded.js turns them into
+// exceptions.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"strings"
+	"syscall/js"
+)
+
+func main() {
+	js.Global().Set("goReloaded", map[string]interface{}{
+		"process": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return call(args, func(req processor.Request) (interface{}, error) { return req.Process() })
+		}),
+		"explain": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return call(args, func(req processor.Request) (interface{}, error) { return req.Explain() })
+		}),
+		"markers": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return toJS(processor.ListMarkers())
+		}),
+	})
+
+	// Keep the functions available until the page or process goes away
+	select {}
+}
+
+// call reads the request that args make and runs fn with it
+func call(args []js.Value, fn func(req processor.Request) (interface{}, error)) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return errorResult(fmt.Errorf("text must be a string"))
+	}
+	req := processor.Request{Text: args[0].String()}
+
+	if len(args) > 1 && !args[1].IsUndefined() && !args[1].IsNull() {
+		if args[1].Type() != js.TypeString {
+			return errorResult(fmt.Errorf("mode must be a string"))
+		}
+		req.Mode = args[1].String()
+	}
+
+	if len(args) > 2 && !args[2].IsUndefined() && !args[2].IsNull() {
+		decoder := json.NewDecoder(strings.NewReader(js.Global().Get("JSON").Call("stringify", args[2]).String()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req.RequestOptions); err != nil {
+			return errorResult(fmt.Errorf("invalid options: %v", err))
+		}
+	}
+	result, err := fn(req)
+	if err != nil {
+		return errorResult(err)
+	}
+	return toJS(result)
+}
+
+func errorResult(err error) interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
+
+// toJS converts v to a JavaScript value through its JSON encoding
+func toJS(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errorResult(err)
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
@@ -0,0 +1,85 @@
+// Runs the WebAssembly build under Node:
+//
+//   node test.js go-reloaded.wasm wasm_exec.js [cases.json]
+//
+// cases.json holds [{text, mode, options, expected}] to check against; the
+// Go test in tests/wasm_test.go writes it from the native processors.
+"use strict";
+
+const assert = require("assert");
+const fs = require("fs");
+const path = require("path");
+
+const [wasmPath, wasmExecPath, casesPath] = process.argv.slice(2);
+if (!wasmPath || !wasmExecPath) {
+  console.error("Usage: node test.js go-reloaded.wasm wasm_exec.js [cases.json]");
+  process.exit(1);
+}
+require(path.resolve(wasmExecPath));
+const { load } = require("./go-reloaded.js");
+
+const tests = [
+  ["process applies markers", (r) => {
+    assert.deepStrictEqual(r.process("it was a apple (up, 2) , ok"), { output: "it was AN APPLE, ok", diagnostics: [] });
+  }],
+  ["process takes a mode and options", (r) => {
+    const result = r.process("a\nb c (up, 3)", "fsm", { scope: "line" });
+    assert.strictEqual(result.output, "a\nB C");
+    assert.deepStrictEqual(result.diagnostics, [
+      { line: 2, column: 5, severity: "warning", message: "(up, 3) reaches past the start of its line; applied to 2 words" },
+    ]);
+  }],
+  ["process takes rules and markers", (r) => {
+    const options = { rules: ["case"], "unknown-markers": "strip", markers: { shout: "up" } };
+    const result = r.process("so (upp) it (shout) 1E (hex)", "pipeline", options);
+    assert.strictEqual(result.output, "so IT 1E (hex)");
+    assert.strictEqual(result.diagnostics[0].message, "unknown marker (upp); did you mean (up)?");
+  }],
+  ["explain lists the rules that changed the text", (r) => {
+    const result = r.explain("so (up) , a owl", "hybrid");
+    assert.strictEqual(result.output, "SO, an owl");
+    assert.deepStrictEqual(result.steps.map((s) => s.rule), ["case", "punctuation", "articles"]);
+    assert.strictEqual(result.steps[0].before, "so (up) , a owl");
+  }],
+  ["markers lists the registry", (r) => {
+    assert.deepStrictEqual(r.markers().map((m) => m.name), ["hex", "bin", "up", "low", "cap"]);
+    assert.strictEqual(r.markers()[2].takesCount, true);
+  }],
+  ["invalid mode throws", (r) => {
+    assert.throws(() => r.process("a", "fast"), /invalid mode "fast"/);
+  }],
+  ["invalid options throw", (r) => {
+    assert.throws(() => r.process("a", "pipeline", { scope: "page" }), /invalid scope "page"/);
+    assert.throws(() => r.process("a", "pipeline", { scop: "line" }), /unknown field "scop"/);
+  }],
+  ["non-string text throws", (r) => {
+    assert.throws(() => r.process(42), /text must be a string/);
+  }],
+];
+
+(async () => {
+  const reloaded = await load(fs.readFileSync(wasmPath));
+  let failed = 0;
+  const check = (name, fn) => {
+    try {
+      fn(reloaded);
+    } catch (err) {
+      failed++;
+      console.error(`FAIL ${name}\n${err.message}`);
+    }
+  };
+
+  for (const [name, fn] of tests) {
+    check(name, fn);
+  }
+  if (casesPath) {
+    for (const c of JSON.parse(fs.readFileSync(casesPath, "utf8"))) {
+      check(`${c.mode} ${JSON.stringify(c.text)}`, (r) => {
+        assert.strictEqual(r.process(c.text, c.mode, c.options).output, c.expected);
+      });
+    }
+  }
+
+  console.log(failed === 0 ? "ok" : `${failed} failed`);
+  process.exit(failed === 0 ? 0 : 1);
+})();
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"path/filepath"
+)
+
+// loadSettings returns the config settings for inputFile, from the file at
+// path or else from the nearest config file above inputFile
+func loadSettings(path, inputFile string) (config.Settings, error) {
+	var cfg *config.Config
+	var err error
+	if path != "" {
+		cfg, err = config.Load(path)
+	} else {
+		cfg, err = config.Discover(filepath.Dir(inputFile))
+	}
+	if err != nil || cfg == nil {
+		return config.Settings{}, err
+	}
+	return cfg.For(inputFile), nil
+}
+
+// processFile processes text, the content of the file at path, with the
+// settings of its config file and mode, if given. It passes each diagnostic
+// to report and fails if any is an error, as with --unknown-markers=error.
+func processFile(configPath, mode, path, text string, report func(rules.Diagnostic)) (string, error) {
+	settings, err := loadSettings(configPath, path)
+	if err != nil {
+		return "", err
+	}
+	if mode != "" {
+		settings.Mode = mode
+	}
+	if settings.Mode == "" {
+		settings.Mode = "pipeline"
+	}
+	opts, err := settings.Options()
+	if err != nil {
+		return "", err
+	}
+	proc, err := processor.New(settings.Mode, opts...)
+	if err != nil {
+		return "", err
+	}
+	result, diagnostics := proc.ProcessWithDiagnostics(text)
+	failed := false
+	for _, d := range diagnostics {
+		report(d)
+		failed = failed || d.Severity == rules.Error
+	}
+	if failed {
+		return "", errors.New("the input has markers that cannot be applied")
+	}
+	return result, nil
+}
+
+// runConfig runs the config subcommands
+func runConfig(args []string) int {
+	if len(args) < 1 || args[0] != "validate" || len(args) > 2 {
+		fmt.Println("Usage: go-reloaded config validate [FILE]")
+		fmt.Println("  Checks FILE, or the nearest .reloaded.yaml or .reloaded.toml")
+		return 1
+	}
+
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	} else {
+		var err error
+		if path, err = config.Find("."); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 2
+		}
+		if path == "" {
+			fmt.Println("Error: no .reloaded.yaml or .reloaded.toml in this directory or above it")
+			return 2
+		}
+	}
+
+	if _, err := config.Load(path); err != nil {
+		var configErr *config.Error
+		if errors.As(err, &configErr) {
+			fmt.Println(configErr)
+			return 1
+		}
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+	fmt.Printf("%s: ok\n", path)
+	return 0
+}
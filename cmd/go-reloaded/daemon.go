@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-reloaded/internal/daemon"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// defaultSocket is where the daemon listens unless --socket is given. It is
+// in $XDG_RUNTIME_DIR, or else in the user's cache directory, so that other
+// users cannot take the path first.
+func defaultSocket() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "go-reloaded.sock"
+		}
+		dir = filepath.Join(cache, "go-reloaded")
+	}
+	return filepath.Join(dir, "go-reloaded.sock")
+}
+
+// runDaemon serves line-delimited JSON-RPC on a Unix socket until interrupted
+func runDaemon(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded daemon", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	socket := flags.String("socket", defaultSocket(), "path of the Unix socket to listen on")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded daemon [--socket PATH]")
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(*socket), 0700); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	// A socket left by a daemon that did not exit cleanly accepts no
+	// connections and can be replaced
+	if _, err := os.Stat(*socket); err == nil {
+		if c, err := net.Dial("unix", *socket); err == nil {
+			c.Close()
+			fmt.Fprintf(os.Stderr, "Error: a daemon is already listening on %s\n", *socket)
+			return 2
+		}
+		os.Remove(*socket)
+	}
+
+	listener, err := daemon.Listen(*socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer listener.Close() // also removes the socket file
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", *socket)
+	if err := daemon.Serve(ctx, listener); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
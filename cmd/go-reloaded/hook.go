@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/hook"
+	"go-reloaded/internal/rules"
+	"os"
+	"path/filepath"
+)
+
+// runHook runs the hook subcommands: install writes a git pre-commit hook,
+// and run is what the hook runs
+func runHook(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "install":
+			return runHookInstall(args[1:])
+		case "run":
+			return runHookRun(args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Usage: go-reloaded hook install [--check] [--force]")
+	fmt.Fprintln(os.Stderr, "       go-reloaded hook run [--check]")
+	return 1
+}
+
+// runHookInstall installs a pre-commit hook that runs this executable
+func runHookInstall(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded hook install", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	check := flags.Bool("check", false, "make the hook fail on files that need processing instead of fixing them")
+	force := flags.Bool("force", false, "replace an existing pre-commit hook")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded hook install [--check] [--force]")
+		return 1
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	command := hook.Quote(executable) + " hook run"
+	if *check {
+		command += " --check"
+	}
+	path, err := hook.Install(".", command, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	fmt.Printf("Installed %s\n", path)
+	return 0
+}
+
+// runHookRun processes the staged files, returning 1 if a commit should
+// not go ahead
+func runHookRun(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded hook run", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	check := flags.Bool("check", false, "fail on files that need processing instead of fixing them")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded hook run [--check]")
+		return 1
+	}
+
+	// Paths are relative to the top of the working tree, where git runs
+	// hooks
+	cfg, err := config.Discover(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	match := func(path string) bool {
+		if cfg != nil {
+			return cfg.Hooked(path)
+		}
+		for _, glob := range config.DefaultHookFiles {
+			if config.MatchGlob(glob, path) {
+				return true
+			}
+		}
+		return false
+	}
+	process := func(path, text string) (string, error) {
+		return processFile("", "", filepath.FromSlash(path), text, func(d rules.Diagnostic) {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, d)
+		})
+	}
+
+	result, err := hook.Run(hook.Config{Dir: ".", Match: match, Process: process, Check: *check, Log: os.Stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(result.Failed) > 0 || *check && len(result.Changed) > 0 {
+		fmt.Fprintln(os.Stderr, "go-reloaded: commit stopped; run go-reloaded on the files above and stage them")
+		return 1
+	}
+	return 0
+}
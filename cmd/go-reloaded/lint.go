@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// lintResult is a finding in one of the linted files
+type lintResult struct {
+	File string `json:"file"`
+	processor.Finding
+}
+
+// runLint reports the problems in files without changing them. It returns
+// 1 if any were found, and 2 for a usage error or a file that could not be
+// read.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded lint", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	format := flags.String("format", "text", "report format: text, json or sarif")
+	configPath := flags.String("config", "", "config file to use instead of looking for one")
+	writers := map[string]func(io.Writer, []lintResult) error{
+		"text":  writeLintText,
+		"json":  writeLintJSON,
+		"sarif": writeLintSARIF,
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 || writers[*format] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded lint [--format text|json|sarif] [--config FILE] FILES...")
+		return 2
+	}
+
+	results := []lintResult{}
+	status := 0
+	for _, file := range flags.Args() {
+		findings, err := lintFile(*configPath, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			status = 2
+			continue
+		}
+		for _, f := range findings {
+			results = append(results, lintResult{File: file, Finding: f})
+		}
+	}
+	if err := writers[*format](os.Stdout, results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if status == 0 && len(results) > 0 {
+		status = 1
+	}
+	return status
+}
+
+// lintFile lints file with the settings of its config file
+func lintFile(configPath, file string) ([]processor.Finding, error) {
+	settings, err := loadSettings(configPath, file)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := settings.Options()
+	if err != nil {
+		return nil, err
+	}
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return processor.Lint(string(text), opts...), nil
+}
+
+// writeLintText writes one "file:line:column: severity: message [rule]"
+// line per finding
+func writeLintText(w io.Writer, results []lintResult) error {
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "%s:%s [%s]\n", r.File, r.Diagnostic, r.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLintJSON writes the findings as a JSON array
+func writeLintJSON(w io.Writer, results []lintResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// The subset of SARIF 2.1.0 that code scanning tools read
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool     `json:"tool"`
+		ColumnKind string        `json:"columnKind"`
+		Results    []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+	}
+)
+
+// writeLintSARIF writes the findings as a SARIF log with one run. Columns
+// count code points, as the diagnostics do.
+func writeLintSARIF(w io.Writer, results []lintResult) error {
+	run := sarifRun{
+		Tool:       sarifTool{Driver: sarifDriver{Name: "go-reloaded"}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	for _, rule := range processor.LintRules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{rule.Description}})
+	}
+	for _, r := range results {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  r.Rule,
+			Level:   r.Severity.String(),
+			Message: sarifMessage{r.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(r.File)},
+				Region:           sarifRegion{StartLine: r.Line, StartColumn: r.Column},
+			}}},
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-reloaded/internal/lsp"
+	"os"
+)
+
+// runLSP serves the Language Server Protocol over stdin and stdout
+func runLSP(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded lsp", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.Bool("stdio", true, "communicate over stdin and stdout (the only transport)")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded lsp [--stdio]")
+		return 1
+	}
+
+	// Stdout carries the protocol, so errors go to stderr
+	if err := lsp.NewServer(os.Stdout).Serve(os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
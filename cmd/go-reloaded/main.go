@@ -1,35 +1,126 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
 	"io/ioutil"
 	"os"
+	"strings"
 )
 
+// subcommands maps subcommand names to their entry points, which take the
+// arguments after the name and return the exit code
+var subcommands = map[string]func(args []string) int{
+	"config": runConfig,
+	"daemon": runDaemon,
+	"hook":   runHook,
+	"lint":   runLint,
+	"lsp":    runLSP,
+	"serve":  runServe,
+	"watch":  runWatch,
+}
+
 func main() {
-	if len(os.Args) != 4 {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
+	flags := flag.NewFlagSet("go-reloaded", flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.Usage = printUsage
+	verifyIdempotent := flags.Bool("verify-idempotent", false, "process the output again and fail on any difference")
+	parallel := flags.Bool("parallel", false, "process paragraphs concurrently")
+	workers := flags.Int("workers", 0, "number of parallel workers (default: one per CPU)")
+	scopeName := flags.String("scope", "unbounded", "how far back numbered markers reach: unbounded, line, sentence or paragraph")
+	whitespaceName := flags.String("whitespace", "preserve", "whitespace policy: preserve, collapse, lf or crlf")
+	localeName := flags.String("locale", "en", "punctuation and quote conventions: en, fr, de or es")
+	configPath := flags.String("config", "", "config file to use instead of looking for one")
+	ruleList := flags.String("rules", "", "comma-separated rules to apply, in order")
+	skipList := flags.String("skip-rules", "", "comma-separated rules to leave out")
+	outputFormat := flags.String("output-format", "text", "what to write: the processed text, or a diff or patch from the input to it")
+	unknownMarkers := flags.String("unknown-markers", "keep", "what to do with unknown, malformed and misplaced markers: keep, strip or error")
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(1)
+	}
+	if flags.NArg() != 2 && flags.NArg() != 3 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
-	outputFile := os.Args[2]
-	mode := os.Args[3]
-
-	// Validate mode
-	var proc processor.Processor
-	switch mode {
-	case "pipeline":
-		proc = processor.NewPipeline()
-	case "fsm":
-		proc = processor.NewFSM()
-	case "hybrid":
-		proc = processor.NewHybrid()
-	default:
+	if err := checkOutputFormat(*outputFormat); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	inputFile := flags.Arg(0)
+	outputFile := flags.Arg(1)
+
+	// Settings come from the config file, overridden by the flags given
+	settings, err := loadSettings(*configPath, inputFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	var ruleErr error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rules":
+			settings.Rules, ruleErr = processor.ParseRules(*ruleList)
+		case "scope":
+			settings.Scope = *scopeName
+		case "whitespace":
+			settings.Whitespace = *whitespaceName
+		case "locale":
+			settings.Locale = *localeName
+		case "unknown-markers":
+			settings.UnknownMarkers = *unknownMarkers
+		}
+	})
+	if ruleErr == nil && *skipList != "" {
+		enabled := settings.Rules
+		if enabled == nil {
+			enabled = processor.RuleNames
+		}
+		settings.Rules, ruleErr = processor.SkipRules(enabled, strings.Split(*skipList, ","))
+	}
+	if ruleErr != nil {
+		fmt.Printf("Error: %v\n", ruleErr)
+		os.Exit(1)
+	}
+	mode := settings.Mode
+	if flags.NArg() == 3 {
+		mode = flags.Arg(2)
+	}
+	if mode == "" {
+		mode = "pipeline"
+	}
+
+	// Validate mode and options
+	if _, err := processor.New(mode); err != nil {
 		fmt.Println("Error: invalid mode. Use one of [pipeline|fsm|hybrid].")
 		os.Exit(1)
 	}
+	opts, err := settings.Options()
+	if err == nil {
+		_, err = processor.New(mode, opts...)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	newProcessor := func() processor.Processor {
+		proc, _ := processor.New(mode, opts...)
+		return proc
+	}
+	proc := newProcessor()
+	if *parallel {
+		proc = processor.NewParallel(newProcessor, *workers)
+	}
 
 	// Read input file
 	input, err := ioutil.ReadFile(inputFile)
@@ -39,10 +130,34 @@ func main() {
 	}
 
 	// Process text
-	result := proc.Process(string(input))
+	var result string
+	var diagnostics []rules.Diagnostic
+	if dp, ok := proc.(processor.DiagnosticProcessor); ok {
+		result, diagnostics = dp.ProcessWithDiagnostics(string(input))
+	} else {
+		result = proc.Process(string(input))
+	}
+	failed := false
+	for _, d := range diagnostics {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", inputFile, d)
+		failed = failed || d.Severity == rules.Error
+	}
+	if failed {
+		// Only --unknown-markers=error reports errors; the output is not written
+		fmt.Println("Error: the input has markers that cannot be applied")
+		os.Exit(4)
+	}
+
+	if *verifyIdempotent {
+		if err := processor.CheckIdempotent(proc, result); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(3)
+		}
+	}
 
 	// Write output file
-	err = ioutil.WriteFile(outputFile, []byte(result), 0644)
+	output := outputFormats[*outputFormat](inputFile, string(input), result)
+	err = ioutil.WriteFile(outputFile, []byte(output), 0644)
 	if err != nil {
 		fmt.Printf("Error writing output file: %v\n", err)
 		os.Exit(2)
@@ -50,9 +165,32 @@ func main() {
 }
 
 func printUsage() {
-	fmt.Println("Usage: go-reloaded <input_file> <output_file> <mode>")
+	fmt.Println("Usage: go-reloaded [options] <input_file> <output_file> [mode]")
+	fmt.Println("       go-reloaded <command> [arguments]")
 	fmt.Println("Modes:")
 	fmt.Println("  pipeline   Sequential modular processor")
 	fmt.Println("  fsm        Finite State Machine processor")
 	fmt.Println("  hybrid     FSM tokenizer + pipeline rules")
-}
\ No newline at end of file
+	fmt.Println("  The mode defaults to the one in the config file, or pipeline")
+	fmt.Println("Commands:")
+	fmt.Println("  config     Check a config file: config validate [FILE]")
+	fmt.Println("  daemon     Serve line-delimited JSON-RPC on a Unix socket (--socket PATH)")
+	fmt.Println("  hook       Install a git pre-commit hook for staged files: hook install [--check] [--force]")
+	fmt.Println("  lint       Report problems without fixing them: lint [--format text|json|sarif] FILES...")
+	fmt.Println("  lsp        Run a language server over stdio for editors")
+	fmt.Println("  serve      Serve the processors over HTTP (--addr, --max-body, --timeout)")
+	fmt.Println("  watch      Reprocess files as they change: watch [--mode MODE] SRC_DIR OUT_DIR")
+	fmt.Println("Options:")
+	fmt.Println("  --verify-idempotent   Process the output again and fail (exit 3) on any difference")
+	fmt.Println("  --parallel            Process paragraphs (split on blank lines) concurrently")
+	fmt.Println("  --workers N           Number of parallel workers (default: one per CPU)")
+	fmt.Println("  --scope SCOPE         How far back (cmd, n) markers reach: unbounded, line, sentence, paragraph")
+	fmt.Println("  --whitespace POLICY   Whitespace policy: preserve (default), collapse, lf, crlf")
+	fmt.Println("  --locale LOCALE       Punctuation and quote conventions: en (default), fr, de, es")
+	fmt.Println("  --rules LIST          Comma-separated rules to apply, in order (default: all)")
+	fmt.Println("  --skip-rules LIST     Comma-separated rules to leave out")
+	fmt.Println("  --unknown-markers P   Unknown or malformed markers: keep (default), strip, or error (exit 4)")
+	fmt.Println("  --output-format F     Write the processed text (text, default), or a unified diff to it from the")
+	fmt.Println("                        input: diff (dated) or patch (undated), applied with patch -p0")
+	fmt.Println("  --config FILE         Config file to use instead of the nearest .reloaded.yaml or .reloaded.toml")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"go-reloaded/internal/diff"
+	"os"
+	"time"
+)
+
+// outputFormats maps the names accepted by --output-format to what they
+// write in place of the processed text. Both diffs name the input file on
+// each side, so "patch -p0" run from the same directory applies them to it.
+var outputFormats = map[string]func(inputFile, input, result string) string{
+	"text": func(inputFile, input, result string) string {
+		return result
+	},
+	// diff dates each side like "diff -u", for reviewing
+	"diff": func(inputFile, input, result string) string {
+		modified := time.Now()
+		if info, err := os.Stat(inputFile); err == nil {
+			modified = info.ModTime()
+		}
+		return diff.Unified(inputFile+"\t"+modified.Format(diffTime), inputFile+"\t"+time.Now().Format(diffTime), input, result)
+	},
+	// patch leaves out the dates, so the same input always gives the same
+	// patch
+	"patch": func(inputFile, input, result string) string {
+		return diff.Unified(inputFile, inputFile, input, result)
+	},
+}
+
+// diffTime is the timestamp layout of "diff -u"
+const diffTime = "2006-01-02 15:04:05.000000000 -0700"
+
+// checkOutputFormat returns an error for an unknown --output-format
+func checkOutputFormat(name string) error {
+	if outputFormats[name] == nil {
+		return fmt.Errorf("invalid output format %q. Use one of [text|diff|patch]", name)
+	}
+	return nil
+}
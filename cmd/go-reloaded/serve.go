@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-reloaded/internal/httpapi"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownGrace is how long in-flight requests get to finish on shutdown
+const shutdownGrace = 10 * time.Second
+
+// runServe serves the HTTP API until interrupted, then shuts down gracefully
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded serve", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	maxBody := flags.Int64("max-body", httpapi.DefaultMaxBodyBytes, "largest request body accepted, in bytes")
+	timeout := flags.Duration("timeout", httpapi.DefaultTimeout, "time a client waits for a reply; processing is not interrupted")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded serve [--addr ADDR] [--max-body BYTES] [--timeout DURATION]")
+		return 1
+	}
+
+	// Listen first, so the address printed is one that accepts connections,
+	// with the port chosen when --addr asks for port 0
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	server := &http.Server{
+		Handler:           httpapi.NewHandler(httpapi.Config{MaxBodyBytes: *maxBody, Timeout: *timeout}),
+		ReadHeaderTimeout: *timeout,
+		WriteTimeout:      *timeout + time.Second, // leave time to write the timeout response
+		IdleTimeout:       time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", listener.Addr())
+
+	select {
+	case err := <-errs:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(os.Stderr, "Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/watch"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runWatch processes the files in a source directory into an output
+// directory, and again whenever they change, until interrupted
+func runWatch(args []string) int {
+	flags := flag.NewFlagSet("go-reloaded watch", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	mode := flags.String("mode", "", "processing mode (default: the one in the config file, or pipeline)")
+	configPath := flags.String("config", "", "config file to use instead of looking for one")
+	debounce := flags.Duration("debounce", watch.DefaultDebounce, "how long a file must go unchanged before it is processed")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: go-reloaded watch [--mode MODE] [--config FILE] [--debounce DURATION] SRC_DIR OUT_DIR")
+		return 1
+	}
+	if *mode != "" {
+		if _, err := processor.New(*mode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	logger := log.New(os.Stderr, "", log.Ltime)
+	process := func(path, text string) (string, error) {
+		return processFile(*configPath, *mode, path, text, func(d rules.Diagnostic) {
+			logger.Printf("%s:%s", path, d)
+		})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	src, out := flags.Arg(0), flags.Arg(1)
+	fmt.Fprintf(os.Stderr, "Watching %s, writing to %s\n", src, out)
+	err := watch.Run(ctx, watch.Config{Src: src, Out: out, Process: process, Debounce: *debounce, Log: logger})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
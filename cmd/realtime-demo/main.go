@@ -4,17 +4,38 @@ import (
 	"bufio"
 	"fmt"
 	"go-reloaded/internal/processor"
-	"go-reloaded/internal/rules"
+	"go-reloaded/internal/repl"
 	"os"
 	"strings"
 )
 
 func main() {
 	fmt.Println("Real-time FSM Demo")
-	fmt.Println("Type text with transformations like: hello(up) or 1E(hex)")
-	fmt.Println("Press Enter to process, 'quit' to exit")
-	fmt.Println()
+	fmt.Println("Type text with transformations like: hello (up) or 1E (hex)")
+
+	fd := int(os.Stdin.Fd())
+	restore, err := repl.MakeRaw(fd)
+	if err != nil {
+		// Not a terminal, so read whole lines instead
+		fmt.Println("Press Enter to process, 'quit' to exit")
+		fmt.Println()
+		runLines()
+		return
+	}
+	defer restore()
+
+	fmt.Print("Enter processes, Ctrl-J adds a line, Left/Right move, Up/Down recall history, Ctrl-D or 'quit' exits\r\n\r\n")
+	session := repl.New(os.Stdin, os.Stdout)
+	session.SetWidth(repl.Width(fd))
+	if err := session.Run(); err != nil {
+		restore()
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
 
+// runLines processes standard input one line at a time
+func runLines() {
 	fsm := processor.NewRealtimeFSM()
 	scanner := bufio.NewScanner(os.Stdin)
 
@@ -29,27 +50,13 @@ func main() {
 			break
 		}
 
-		// Process character by character
+		// Process character by character, then flush the rest
 		var output strings.Builder
 		for _, char := range input {
-			result := fsm.ProcessChar(char)
-			if result != "" {
-				output.WriteString(result)
-			}
+			output.WriteString(fsm.ProcessChar(char))
 		}
-
-		// Show current buffer (incomplete input)
-		buffer := fsm.GetCurrentBuffer()
-		if buffer != "" {
-			output.WriteString(buffer)
-		}
-
-		// Apply all final rules to complete output
-		finalOutput := output.String()
-		finalOutput = rules.CleanQuotes(finalOutput)
-		finalOutput = rules.FixPunctuation(finalOutput)
-		finalOutput = rules.FixArticles(finalOutput)
-		fmt.Printf("Output: %s\n\n", finalOutput)
+		output.WriteString(fsm.Flush())
+		fmt.Printf("Output: %s\n\n", output.String())
 		fsm.Reset() // Reset for next input
 	}
-}
\ No newline at end of file
+}
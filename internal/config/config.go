@@ -0,0 +1,286 @@
+// Package config loads go-reloaded settings from a .reloaded.yaml or
+// .reloaded.toml file, found by looking up the directory tree from the file
+// being processed.
+//
+// A config file sets the same options as the command line, plus the rules to
+// apply and their order, article exceptions, marker definitions, and
+// overrides for files matching a glob:
+//
+//	mode: fsm
+//	locale: en
+//	unknown-markers: strip
+//	rules: [case, numbers, quotes, punctuation, articles, whitespace]
+//	articles:
+//	  a: [university, one]
+//	  an: [hour]
+//	markers:
+//	  upper: up
+//	  shout: (up) !
+//	overrides:
+//	  - files: "docs/fr/**"
+//	    locale: fr
+//	hook:
+//	  files: ["*.txt", "docs/**"]
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileNames lists the config file names looked for in each directory, in
+// order of preference
+var FileNames = []string{".reloaded.yaml", ".reloaded.yml", ".reloaded.toml"}
+
+// Settings are the options a config file sets. Empty fields are unset and
+// keep the defaults.
+type Settings struct {
+	Mode       string
+	Scope      string
+	Whitespace string
+	Locale     string
+	// UnknownMarkers names the rules.MarkerPolicy for unknown, malformed and
+	// misplaced markers
+	UnknownMarkers string
+	// Rules lists the enabled rules in order, or is nil for all of them
+	Rules    []string
+	Articles rules.ArticleExceptions
+	// Markers maps the names of user-defined markers to their definitions,
+	// see rules.MarkerDefinitions
+	Markers map[string]string
+}
+
+// Override applies settings to the files matching a glob
+type Override struct {
+	// Files is matched against paths relative to the directory of the
+	// config file, see MatchGlob
+	Files string
+	Settings
+}
+
+// Hook configures the git pre-commit hook
+type Hook struct {
+	// Files are globs of the staged files the hook processes, matched like
+	// Override.Files. Empty means DefaultHookFiles.
+	Files []string
+}
+
+// DefaultHookFiles are the files the pre-commit hook processes unless the
+// config file says otherwise
+var DefaultHookFiles = []string{"*.txt"}
+
+// Config is a loaded config file
+type Config struct {
+	Path string
+	Settings
+	Overrides []Override
+	Hook      Hook
+}
+
+// Problem is a mistake in a config file
+type Problem struct {
+	Line    int
+	Message string
+}
+
+// Error lists every problem found in a config file
+type Error struct {
+	Path     string
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = fmt.Sprintf("%s:%d: %s", e.Path, p.Line, p.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Load reads and validates the config file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse validates the contents of a config file. The format is taken from
+// the extension of path.
+func Parse(path string, data []byte) (*Config, error) {
+	var root *node
+	var err error
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		root, err = parseYAML(string(data))
+	case ".toml":
+		root, err = parseTOML(string(data))
+	default:
+		return nil, fmt.Errorf("%s: unknown config format; use a .yaml or .toml file", path)
+	}
+	var syntaxErr *syntaxError
+	if errors.As(err, &syntaxErr) {
+		return nil, &Error{Path: path, Problems: []Problem{{Line: syntaxErr.line, Message: syntaxErr.message}}}
+	}
+
+	d := &decoder{}
+	cfg := &Config{Path: path}
+	cfg.Settings, cfg.Overrides = d.decode(root, true)
+	cfg.Hook = d.hookSettings
+	d.checkMarkers(cfg.Markers)
+	if len(d.problems) > 0 {
+		return nil, &Error{Path: path, Problems: d.problems}
+	}
+	return cfg, nil
+}
+
+// Find returns the path of the config file in dir or the nearest directory
+// above it, or "" if there is none
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Discover loads the config file that applies to dir, or returns nil if
+// there is none
+func Discover(dir string) (*Config, error) {
+	path, err := Find(dir)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// For returns the settings for file: the top-level settings with every
+// matching override applied in order. Overrides only match files under the
+// directory of the config file.
+func (c *Config) For(file string) Settings {
+	settings := c.Settings
+	rel, ok := c.relative(file)
+	if !ok {
+		return settings
+	}
+	for _, o := range c.Overrides {
+		if MatchGlob(o.Files, rel) {
+			settings = settings.merge(o.Settings)
+		}
+	}
+	return settings
+}
+
+// Hooked reports whether the pre-commit hook processes file, which must be
+// under the directory of the config file
+func (c *Config) Hooked(file string) bool {
+	rel, ok := c.relative(file)
+	if !ok {
+		return false
+	}
+	globs := c.Hook.Files
+	if len(globs) == 0 {
+		globs = DefaultHookFiles
+	}
+	for _, glob := range globs {
+		if MatchGlob(glob, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// relative returns the slash-separated path of file relative to the
+// directory of the config file, and false if file is not under it
+func (c *Config) relative(file string) (string, bool) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+	dir, err := filepath.Abs(filepath.Dir(c.Path))
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// merge returns s with the fields set in o replaced. Marker definitions are
+// merged by name.
+func (s Settings) merge(o Settings) Settings {
+	if o.Mode != "" {
+		s.Mode = o.Mode
+	}
+	if o.Scope != "" {
+		s.Scope = o.Scope
+	}
+	if o.Whitespace != "" {
+		s.Whitespace = o.Whitespace
+	}
+	if o.Locale != "" {
+		s.Locale = o.Locale
+	}
+	if o.UnknownMarkers != "" {
+		s.UnknownMarkers = o.UnknownMarkers
+	}
+	if o.Rules != nil {
+		s.Rules = o.Rules
+	}
+	if o.Articles.A != nil {
+		s.Articles.A = o.Articles.A
+	}
+	if o.Articles.An != nil {
+		s.Articles.An = o.Articles.An
+	}
+	if len(o.Markers) > 0 {
+		markers := make(map[string]string, len(s.Markers)+len(o.Markers))
+		for name, target := range s.Markers {
+			markers[name] = target
+		}
+		for name, target := range o.Markers {
+			markers[name] = target
+		}
+		s.Markers = markers
+	}
+	return s
+}
+
+// Options returns the processor options for the settings other than Mode
+func (s Settings) Options() ([]processor.Option, error) {
+	opts, err := processor.RequestOptions{
+		Scope:          s.Scope,
+		Whitespace:     s.Whitespace,
+		Locale:         s.Locale,
+		UnknownMarkers: s.UnknownMarkers,
+		Rules:          s.Rules,
+		Markers:        s.Markers,
+	}.Options()
+	if err != nil {
+		return nil, err
+	}
+	if s.Articles.A != nil || s.Articles.An != nil {
+		opts = append(opts, processor.WithArticleExceptions(s.Articles))
+	}
+	return opts, nil
+}
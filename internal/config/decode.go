@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"sort"
+	"strings"
+)
+
+// decoder turns a parsed file into settings, collecting every problem
+// instead of stopping at the first
+type decoder struct {
+	problems []Problem
+	// markers holds the marker tables read, which are checked once the
+	// top-level definitions are known
+	markerTables []markerTable
+	// hookSettings is the top-level hook table
+	hookSettings Hook
+}
+
+// markerTable is a table of marker definitions and the line of each
+type markerTable struct {
+	definitions rules.MarkerDefinitions
+	lines       map[string]int
+	override    bool
+}
+
+func (d *decoder) problem(line int, format string, args ...interface{}) {
+	d.problems = append(d.problems, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// decode reads the settings in a table, and its overrides if top is set
+func (d *decoder) decode(table *node, top bool) (Settings, []Override) {
+	var s Settings
+	var overrides []Override
+	for _, key := range table.keys {
+		value := table.fields[key]
+		switch key {
+		case "mode":
+			if name, ok := d.scalar(key, value); ok {
+				if _, err := processor.New(name); err != nil {
+					d.problem(value.line, "%v", err)
+				}
+				s.Mode = name
+			}
+		case "scope":
+			if name, ok := d.scalar(key, value); ok {
+				if _, err := rules.ParseScope(name); err != nil {
+					d.problem(value.line, "%v", err)
+				}
+				s.Scope = name
+			}
+		case "whitespace":
+			if name, ok := d.scalar(key, value); ok {
+				if _, err := rules.ParseWhitespace(name); err != nil {
+					d.problem(value.line, "%v", err)
+				}
+				s.Whitespace = name
+			}
+		case "locale":
+			if name, ok := d.scalar(key, value); ok {
+				if _, err := rules.ParseLocale(name); err != nil {
+					d.problem(value.line, "%v", err)
+				}
+				s.Locale = name
+			}
+		case "unknown-markers":
+			if name, ok := d.scalar(key, value); ok {
+				if _, err := rules.ParseMarkerPolicy(name); err != nil {
+					d.problem(value.line, "%v", err)
+				}
+				s.UnknownMarkers = name
+			}
+		case "rules":
+			s.Rules = d.rules(value)
+		case "articles":
+			s.Articles = d.articles(value)
+		case "markers":
+			s.Markers = d.markers(value, top)
+		case "hook":
+			if !top {
+				d.problem(value.line, "hook cannot be set in an override")
+				continue
+			}
+			d.hookSettings = d.hook(value)
+		case "overrides":
+			if !top {
+				d.problem(value.line, "overrides cannot be nested")
+				continue
+			}
+			overrides = d.overrides(value)
+		default:
+			d.problem(value.line, "unknown key %q", key)
+		}
+	}
+	return s, overrides
+}
+
+func (d *decoder) scalar(key string, value *node) (string, bool) {
+	if value.kind != scalarNode {
+		d.problem(value.line, "%s must be a single value", key)
+		return "", false
+	}
+	return value.value, true
+}
+
+func (d *decoder) list(key string, value *node) ([]string, bool) {
+	if value.kind != listNode {
+		d.problem(value.line, "%s must be a list", key)
+		return nil, false
+	}
+	items := make([]string, 0, len(value.items))
+	for _, item := range value.items {
+		if item.kind != scalarNode {
+			d.problem(item.line, "%s must be a list of names", key)
+			return nil, false
+		}
+		items = append(items, item.value)
+	}
+	return items, true
+}
+
+func (d *decoder) rules(value *node) []string {
+	names, ok := d.list("rules", value)
+	if !ok {
+		return nil
+	}
+	if err := processor.ValidateRules(names); err != nil {
+		for _, problem := range strings.Split(err.Error(), "\n") {
+			d.problem(value.line, "%s", problem)
+		}
+	}
+	return names
+}
+
+func (d *decoder) articles(value *node) rules.ArticleExceptions {
+	var exceptions rules.ArticleExceptions
+	if value.kind != tableNode {
+		d.problem(value.line, "articles must have a and an lists")
+		return exceptions
+	}
+	for _, key := range value.keys {
+		words, ok := d.list("articles."+key, value.fields[key])
+		if !ok {
+			continue
+		}
+		for _, word := range words {
+			if !isWord(word) {
+				d.problem(value.fields[key].line, "article exception %q must be a single word", word)
+			}
+		}
+		switch key {
+		case "a":
+			exceptions.A = words
+		case "an":
+			exceptions.An = words
+		default:
+			d.problem(value.fields[key].line, "unknown key %q in articles. Use a or an", key)
+		}
+	}
+	return exceptions
+}
+
+func (d *decoder) markers(value *node, top bool) map[string]string {
+	if value.kind != tableNode {
+		d.problem(value.line, "markers must map marker names to their definitions")
+		return nil
+	}
+	table := markerTable{definitions: make(rules.MarkerDefinitions, len(value.keys)), lines: make(map[string]int), override: !top}
+	for _, name := range value.keys {
+		if definition, ok := d.scalar("marker "+name, value.fields[name]); ok {
+			table.definitions[name] = definition
+			table.lines[name] = value.fields[name].line
+		}
+	}
+	d.markerTables = append(d.markerTables, table)
+	return table.definitions
+}
+
+// checkMarkers checks the marker definitions read. Overrides may use the
+// markers defined at the top level.
+func (d *decoder) checkMarkers(top map[string]string) {
+	for _, table := range d.markerTables {
+		definitions := table.definitions
+		if table.override {
+			definitions = Settings{Markers: top}.merge(Settings{Markers: definitions}).Markers
+		}
+		for name, line := range table.lines {
+			if err := rules.MarkerDefinitions(definitions).Check(name); err != nil {
+				d.problem(line, "%v", err)
+			}
+		}
+	}
+	sort.SliceStable(d.problems, func(i, j int) bool { return d.problems[i].Line < d.problems[j].Line })
+}
+
+func (d *decoder) hook(value *node) Hook {
+	var hook Hook
+	if value.kind != tableNode {
+		d.problem(value.line, "hook must have a files list")
+		return hook
+	}
+	for _, key := range value.keys {
+		if key != "files" {
+			d.problem(value.fields[key].line, "unknown key %q in hook. Use files", key)
+			continue
+		}
+		globs, _ := d.list("hook.files", value.fields[key])
+		for _, glob := range globs {
+			if !validGlob(glob) {
+				d.problem(value.fields[key].line, "invalid files glob %q", glob)
+			}
+		}
+		hook.Files = globs
+	}
+	return hook
+}
+
+func (d *decoder) overrides(value *node) []Override {
+	if value.kind != listNode {
+		d.problem(value.line, "overrides must be a list")
+		return nil
+	}
+	var overrides []Override
+	for _, item := range value.items {
+		if item.kind != tableNode {
+			d.problem(item.line, "each override must set files and the settings for them")
+			continue
+		}
+		files, ok := item.fields["files"]
+		if !ok {
+			d.problem(item.line, "override has no files glob")
+			continue
+		}
+		var o Override
+		if o.Files, ok = d.scalar("files", files); ok && !validGlob(o.Files) {
+			d.problem(files.line, "invalid files glob %q", o.Files)
+		}
+
+		settings := newTable(item.line)
+		for _, key := range item.keys {
+			if key != "files" {
+				settings.set(key, item.fields[key])
+			}
+		}
+		o.Settings, _ = d.decode(settings, false)
+		overrides = append(overrides, o)
+	}
+	return overrides
+}
+
+// isWord reports whether s is a non-empty run of ASCII letters
+func isWord(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// validGlob reports whether pattern is a valid glob
+func validGlob(pattern string) bool {
+	for _, part := range strings.Split(pattern, "/") {
+		if part == "**" {
+			continue
+		}
+		if _, err := path.Match(part, ""); err != nil {
+			return false
+		}
+	}
+	return pattern != ""
+}
+
+// MatchGlob reports whether the slash-separated path name matches pattern.
+// Patterns use path.Match syntax, with "**" matching any number of
+// directories. A pattern without a slash matches the last element of name,
+// so "*.md" matches markdown files in any directory.
+func MatchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchParts(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// nodeKind is the kind of a parsed value
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	listNode
+	tableNode
+)
+
+// node is a value parsed from a YAML or TOML file, with the line it
+// started on
+type node struct {
+	kind  nodeKind
+	line  int
+	value string
+	items []*node
+	// keys lists the keys of a table in file order
+	keys   []string
+	fields map[string]*node
+}
+
+func newTable(line int) *node {
+	return &node{kind: tableNode, line: line, fields: make(map[string]*node)}
+}
+
+// set adds a key to a table, failing if it is already set
+func (n *node) set(key string, value *node) error {
+	if _, ok := n.fields[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	n.keys = append(n.keys, key)
+	n.fields[key] = value
+	return nil
+}
+
+// syntaxError is a problem parsing the file at a line
+type syntaxError struct {
+	line    int
+	message string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.message)
+}
+
+func syntaxErrorf(line int, format string, args ...interface{}) error {
+	return &syntaxError{line: line, message: fmt.Sprintf(format, args...)}
+}
+
+// stripComment removes a # comment that is outside quotes and starts the
+// line or follows whitespace
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t\r")
+}
+
+// splitItems splits the inside of a [a, b] list at commas outside quotes
+func splitItems(inner string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, inner[start:i])
+			start = i + 1
+		}
+	}
+	// A trailing comma is allowed
+	if strings.TrimSpace(inner[start:]) != "" {
+		items = append(items, inner[start:])
+	}
+	return items
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML a config file needs: key = value
+// pairs, [table] and [[array of tables]] headers, strings, lists of strings
+// that may span lines, and comments. Other values are kept as their text.
+func parseTOML(data string) (*node, error) {
+	root := newTable(1)
+	current := root
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		num := i + 1
+		text := strings.TrimSpace(stripComment(lines[i]))
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") && !strings.Contains(text, "=") {
+			var err error
+			if current, err = tomlHeader(root, text, num); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		key, rest, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, syntaxErrorf(num, "expected \"key = value\", got %q", text)
+		}
+		rest = strings.TrimSpace(rest)
+		// A list continues until its brackets balance
+		for strings.HasPrefix(rest, "[") && !balanced(rest) && i+1 < len(lines) {
+			i++
+			rest += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+
+		path, err := tomlKey(strings.TrimSpace(key), num)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseTOMLValue(rest, num)
+		if err != nil {
+			return nil, err
+		}
+		table := current
+		for _, part := range path[:len(path)-1] {
+			if table, err = subtable(table, part, num); err != nil {
+				return nil, err
+			}
+		}
+		if err := table.set(path[len(path)-1], value); err != nil {
+			return nil, syntaxErrorf(num, "%v", err)
+		}
+	}
+	return root, nil
+}
+
+// tomlHeader returns the table a [table] or [[array]] header starts
+func tomlHeader(root *node, text string, num int) (*node, error) {
+	array := strings.HasPrefix(text, "[[")
+	inner := strings.TrimPrefix(text, "[")
+	if array {
+		inner = strings.TrimSuffix(strings.TrimPrefix(inner, "["), "]]")
+	} else {
+		inner = strings.TrimSuffix(inner, "]")
+	}
+	if strings.ContainsAny(inner, "[]") {
+		return nil, syntaxErrorf(num, "invalid table header %q", text)
+	}
+	path, err := tomlKey(strings.TrimSpace(inner), num)
+	if err != nil {
+		return nil, err
+	}
+
+	table := root
+	for _, part := range path[:len(path)-1] {
+		if table, err = subtable(table, part, num); err != nil {
+			return nil, err
+		}
+	}
+	last := path[len(path)-1]
+	if !array {
+		if existing, ok := table.fields[last]; ok && existing.kind == tableNode {
+			return nil, syntaxErrorf(num, "table [%s] is defined twice", inner)
+		}
+		next := newTable(num)
+		return next, table.set(last, next)
+	}
+
+	list, ok := table.fields[last]
+	if !ok {
+		list = &node{kind: listNode, line: num}
+		table.set(last, list)
+	} else if list.kind != listNode {
+		return nil, syntaxErrorf(num, "%q is not an array of tables", last)
+	}
+	next := newTable(num)
+	list.items = append(list.items, next)
+	return next, nil
+}
+
+// subtable returns the table under key, creating it if needed. For an array
+// of tables it is the last one.
+func subtable(table *node, key string, num int) (*node, error) {
+	next, ok := table.fields[key]
+	if !ok {
+		next = newTable(num)
+		table.set(key, next)
+	}
+	if next.kind == listNode && len(next.items) > 0 {
+		next = next.items[len(next.items)-1]
+	}
+	if next.kind != tableNode {
+		return nil, syntaxErrorf(num, "%q is not a table", key)
+	}
+	return next, nil
+}
+
+// tomlKey splits a possibly dotted key into its parts
+func tomlKey(key string, num int) ([]string, error) {
+	var parts []string
+	for _, part := range strings.Split(key, ".") {
+		part = strings.TrimSpace(part)
+		if unquoted, err := strconv.Unquote(part); err == nil && strings.HasPrefix(part, "\"") {
+			part = unquoted
+		} else if strings.HasPrefix(part, "'") && strings.HasSuffix(part, "'") && len(part) >= 2 {
+			part = part[1 : len(part)-1]
+		}
+		if part == "" {
+			return nil, syntaxErrorf(num, "invalid key %q", key)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// balanced reports whether the brackets of a list outside strings match
+func balanced(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth == 0
+}
+
+func parseTOMLValue(text string, num int) (*node, error) {
+	switch {
+	case text == "":
+		return nil, syntaxErrorf(num, "missing value")
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, syntaxErrorf(num, "unterminated list %q", text)
+		}
+		list := &node{kind: listNode, line: num}
+		for _, item := range splitItems(text[1 : len(text)-1]) {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				return nil, syntaxErrorf(num, "empty list item in %q", text)
+			}
+			value, err := parseTOMLValue(item, num)
+			if err != nil {
+				return nil, err
+			}
+			if value.kind != scalarNode {
+				return nil, syntaxErrorf(num, "nested lists are not supported")
+			}
+			list.items = append(list.items, value)
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, syntaxErrorf(num, "inline tables are not supported; use a [table]")
+	case strings.HasPrefix(text, "\""):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, syntaxErrorf(num, "invalid string %s", text)
+		}
+		return &node{kind: scalarNode, line: num, value: value}, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") || strings.Contains(text[1:len(text)-1], "'") {
+			return nil, syntaxErrorf(num, "invalid string %s", text)
+		}
+		return &node{kind: scalarNode, line: num, value: text[1 : len(text)-1]}, nil
+	}
+	return &node{kind: scalarNode, line: num, value: text}, nil
+}
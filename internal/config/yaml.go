@@ -0,0 +1,194 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML a config file needs: nested block
+// mappings and sequences, [a, b] lists, plain and quoted strings, and
+// comments
+func parseYAML(data string) (*node, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(data, "\n") {
+		text := stripComment(raw)
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, syntaxErrorf(i+1, "indent with spaces, not tabs")
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+
+	if len(p.lines) == 0 {
+		return newTable(1), nil
+	}
+	if isListItem(p.lines[0].text) {
+		return nil, syntaxErrorf(p.lines[0].num, "the top level must be a mapping")
+	}
+	root, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, syntaxErrorf(p.lines[p.pos].num, "unexpected indentation")
+	}
+	return root, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseBlock parses the mapping or sequence whose lines start at indent
+func (p *yamlParser) parseBlock(indent int) (*node, error) {
+	if isListItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (*node, error) {
+	table := newTable(p.lines[p.pos].num)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		key, rest, ok := cutYAMLKey(line.text)
+		if !ok {
+			return nil, syntaxErrorf(line.num, "expected \"key: value\", got %q", line.text)
+		}
+		p.pos++
+
+		var value *node
+		var err error
+		switch {
+		case rest != "":
+			value, err = parseYAMLValue(rest, line.num)
+		case p.pos < len(p.lines) && (p.lines[p.pos].indent > indent ||
+			p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text)):
+			// The value is the block below the key; a sequence may
+			// start at the key's own indentation
+			value, err = p.parseBlock(p.lines[p.pos].indent)
+		default:
+			value = &node{kind: scalarNode, line: line.num}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := table.set(key, value); err != nil {
+			return nil, syntaxErrorf(line.num, "%v", err)
+		}
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, syntaxErrorf(p.lines[p.pos].num, "unexpected indentation")
+	}
+	return table, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (*node, error) {
+	list := &node{kind: listNode, line: p.lines[p.pos].num}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+
+		var item *node
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				return nil, syntaxErrorf(line.num, "empty list item")
+			}
+			item, err = p.parseBlock(p.lines[p.pos].indent)
+		case isMappingEntry(rest):
+			// "- key: value" starts a mapping whose keys line up with key
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.text) - len(rest), text: rest}
+			item, err = p.parseMapping(p.lines[p.pos].indent)
+		default:
+			p.pos++
+			item, err = parseYAMLValue(rest, line.num)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, item)
+	}
+	return list, nil
+}
+
+// isMappingEntry reports whether text is "key: value" rather than a scalar
+func isMappingEntry(text string) bool {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") || strings.HasPrefix(text, "[") {
+		return false
+	}
+	_, _, ok := cutYAMLKey(text)
+	return ok
+}
+
+// cutYAMLKey splits "key: value" or "key:"
+func cutYAMLKey(text string) (key, rest string, ok bool) {
+	if i := strings.Index(text, ": "); i > 0 {
+		key, rest = text[:i], strings.TrimSpace(text[i+2:])
+	} else if strings.HasSuffix(text, ":") && len(text) > 1 {
+		key = text[:len(text)-1]
+	} else {
+		return "", "", false
+	}
+	key = strings.TrimSpace(key)
+	if unquoted, err := strconv.Unquote(key); err == nil && strings.HasPrefix(key, "\"") {
+		key = unquoted
+	}
+	return key, rest, key != ""
+}
+
+// parseYAMLValue parses a value written on the same line as its key
+func parseYAMLValue(text string, line int) (*node, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, syntaxErrorf(line, "unterminated list %q", text)
+		}
+		list := &node{kind: listNode, line: line}
+		for _, item := range splitItems(text[1 : len(text)-1]) {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				return nil, syntaxErrorf(line, "empty list item in %q", text)
+			}
+			value, err := parseYAMLValue(item, line)
+			if err != nil {
+				return nil, err
+			}
+			if value.kind != scalarNode {
+				return nil, syntaxErrorf(line, "nested lists are not supported")
+			}
+			list.items = append(list.items, value)
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, syntaxErrorf(line, "{...} mappings are not supported; write one key per line")
+	case strings.HasPrefix(text, "\""):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, syntaxErrorf(line, "invalid quoted string %s", text)
+		}
+		return &node{kind: scalarNode, line: line, value: value}, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, syntaxErrorf(line, "invalid quoted string %s", text)
+		}
+		return &node{kind: scalarNode, line: line, value: strings.ReplaceAll(text[1:len(text)-1], "''", "'")}, nil
+	}
+	return &node{kind: scalarNode, line: line, value: text}, nil
+}
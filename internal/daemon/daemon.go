@@ -0,0 +1,206 @@
+// Package daemon serves the processors to long-running clients such as
+// editor hooks. Each line a client sends is a JSON-RPC 2.0 request, and
+// each reply is one line of JSON.
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"io"
+	"net"
+	"sync"
+)
+
+// MaxLineBytes is the longest request line accepted
+const MaxLineBytes = 16 << 20
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   responseError    `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve accepts clients on l, each in its own goroutine, until ctx is done.
+// It then stops accepting, lets requests in progress finish and closes the
+// connections.
+func Serve(ctx context.Context, l net.Listener) error {
+	var mu sync.Mutex
+	conns := make(map[*conn]bool)
+	closing := false
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		l.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		closing = true
+		for c := range conns {
+			c.close()
+		}
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		c := &conn{rw: nc}
+		mu.Lock()
+		if closing {
+			mu.Unlock()
+			nc.Close()
+			continue
+		}
+		conns[c] = true
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ServeConn(c)
+			nc.Close()
+			mu.Lock()
+			delete(conns, c)
+			mu.Unlock()
+		}()
+	}
+}
+
+// conn is a client connection. busy is held while a request is handled, so
+// closing the connection waits for the reply to be written.
+type conn struct {
+	rw   io.ReadWriteCloser
+	busy sync.Mutex
+}
+
+func (c *conn) Read(p []byte) (int, error) { return c.rw.Read(p) }
+
+func (c *conn) Write(p []byte) (int, error) { return c.rw.Write(p) }
+
+func (c *conn) close() {
+	c.busy.Lock()
+	defer c.busy.Unlock()
+	c.rw.Close()
+}
+
+// ServeConn answers the requests read from rw until it ends
+func ServeConn(rw io.ReadWriter) error {
+	scanner := bufio.NewScanner(rw)
+	scanner.Buffer(make([]byte, 64*1024), MaxLineBytes)
+	encoder := json.NewEncoder(rw)
+	c, _ := rw.(*conn)
+
+	for scanner.Scan() {
+		if c != nil {
+			c.busy.Lock()
+		}
+		resp := handle(scanner.Bytes())
+		var err error
+		if resp != nil {
+			err = encoder.Encode(resp)
+		}
+		if c != nil {
+			c.busy.Unlock()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		// The rest of the line cannot be skipped reliably, so give up
+		encoder.Encode(errorReply(nil, codeInvalidRequest, fmt.Sprintf("request longer than %d bytes", MaxLineBytes)))
+	}
+	return scanner.Err()
+}
+
+// handle answers one request line, or returns nil for a notification
+func handle(line []byte) interface{} {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorReply(nil, codeParseError, err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorReply(req.ID, codeInvalidRequest, `expected a JSON-RPC 2.0 request with a method`)
+	}
+
+	result, code, err := call(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return errorReply(req.ID, code, err.Error())
+	}
+	return response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// call runs a method, returning its result or an error code and error
+func call(method string, rawParams json.RawMessage) (interface{}, int, error) {
+	switch method {
+	case "process", "explain":
+		var params processor.Request
+		if len(rawParams) > 0 {
+			if err := json.Unmarshal(rawParams, &params); err != nil {
+				return nil, codeInvalidParams, err
+			}
+		}
+		var result interface{}
+		var err error
+		if method == "explain" {
+			result, err = params.Explain()
+		} else {
+			result, err = params.Process()
+		}
+		if err != nil {
+			return nil, codeInvalidParams, err
+		}
+		return result, 0, nil
+
+	case "listMarkers":
+		return processor.ListMarkers(), 0, nil
+	}
+	return nil, codeMethodNotFound, fmt.Errorf("method %q not found", method)
+}
+
+func errorReply(id *json.RawMessage, code int, message string) errorResponse {
+	return errorResponse{JSONRPC: "2.0", ID: id, Error: responseError{Code: code, Message: message}}
+}
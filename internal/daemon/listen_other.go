@@ -0,0 +1,11 @@
+//go:build !unix
+
+package daemon
+
+import "net"
+
+// Listen listens on a new Unix socket at path. There is no umask here, so
+// the socket is protected by the permissions of its directory.
+func Listen(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
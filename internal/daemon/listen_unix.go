@@ -0,0 +1,17 @@
+//go:build unix
+
+package daemon
+
+import (
+	"net"
+	"syscall"
+)
+
+// Listen listens on a new Unix socket at path that only the current user
+// can connect to. The umask is tightened while the socket is created, so it
+// never exists with looser permissions.
+func Listen(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
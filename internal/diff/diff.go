@@ -0,0 +1,176 @@
+// Package diff writes unified diffs between two versions of a text, in the
+// format read by patch(1) and git apply.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change
+const Context = 3
+
+// op is one line of an edit script
+type op struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Unified returns a unified diff that turns old into new, with oldHeader
+// and newHeader as the text of its "---" and "+++" lines. It returns "" if
+// the texts are equal.
+func Unified(oldHeader, newHeader, old, new string) string {
+	if old == new {
+		return ""
+	}
+	script := edits(splitLines(old), splitLines(new))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldHeader, newHeader)
+	for start := 0; start < len(script); {
+		// Find the next change and the end of its hunk, which runs on
+		// while changes are less than two contexts apart
+		first := start
+		for first < len(script) && script[first].kind == ' ' {
+			first++
+		}
+		if first == len(script) {
+			break
+		}
+		end, unchanged := first, 0
+		for i := first; i < len(script) && unchanged <= 2*Context; i++ {
+			if script[i].kind == ' ' {
+				unchanged++
+				continue
+			}
+			end, unchanged = i+1, 0
+		}
+		begin := max(first-Context, start)
+		end = min(end+Context, len(script))
+		writeHunk(&out, script, begin, end)
+		start = end
+	}
+	return out.String()
+}
+
+// writeHunk writes the lines of script from begin to end as a hunk
+func writeHunk(out *strings.Builder, script []op, begin, end int) {
+	oldStart, newStart := 1, 1
+	for _, o := range script[:begin] {
+		if o.kind != '+' {
+			oldStart++
+		}
+		if o.kind != '-' {
+			newStart++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, o := range script[begin:end] {
+		if o.kind != '+' {
+			oldCount++
+		}
+		if o.kind != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range script[begin:end] {
+		out.WriteByte(o.kind)
+		out.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// side starts at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text after each line break, keeping the breaks
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits returns the shortest edit script from a to b, using Myers' algorithm
+func edits(a, b []string) []op {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		// Backtracking only reads diagonals -d-1 to d+1 of this step
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack follows the furthest reaching paths saved in trace back from
+// the end of a and b to build the edit script. trace[d] holds diagonal k at
+// index k+d+1.
+func backtrack(a, b []string, trace [][]int, d int) []op {
+	x, y := len(a), len(b)
+	script := make([]op, 0, x+y)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[k-1+d+1] < v[k+1+d+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			script = append(script, op{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			script = append(script, op{'+', b[y]})
+		} else {
+			x--
+			script = append(script, op{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x, y = x-1, y-1
+		script = append(script, op{' ', a[x]})
+	}
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
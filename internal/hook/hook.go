@@ -0,0 +1,204 @@
+// Package hook runs go-reloaded from a git pre-commit hook. It works on the
+// content staged for the commit, read from and written back to the index
+// with plain git commands, so unstaged edits are never committed by it.
+package hook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// marker is the line that identifies a hook written by Install
+const marker = "# go-reloaded pre-commit hook"
+
+// Install writes a pre-commit hook into the hooks directory of the git
+// repository containing dir, honouring core.hooksPath. The hook runs
+// command, which must be safe to pass to sh. An existing hook that Install
+// did not write is only replaced if force is set.
+func Install(dir, command string, force bool) (string, error) {
+	hooks, err := git(dir, nil, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooksDir := strings.TrimSpace(string(hooks))
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(dir, hooksDir)
+	}
+	path := filepath.Join(hooksDir, "pre-commit")
+	if existing, err := os.ReadFile(path); err == nil && !force && !bytes.Contains(existing, []byte(marker)) {
+		return "", fmt.Errorf("%s already exists; use --force to replace it", path)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", err
+	}
+	script := "#!/bin/sh\n" + marker + `, written by "go-reloaded hook install"` + "\nexec " + command + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file
+	return path, os.Chmod(path, 0755)
+}
+
+// Quote quotes s for sh
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Config configures Run
+type Config struct {
+	// Dir is a directory in the repository
+	Dir string
+	// Match reports whether a staged file is processed, given its path
+	// relative to the top of the working tree
+	Match func(path string) bool
+	// Process returns the processed text of the staged file at path, which
+	// is relative to the top of the working tree
+	Process func(path, text string) (string, error)
+	// Check only reports the files that processing would change. Otherwise
+	// their processed text is staged in place of the original.
+	Check bool
+	// Log receives a line for each file changed or failed. Nil discards them.
+	Log io.Writer
+}
+
+// Result lists the files a Run changed, or would change in check mode, and
+// the files that could not be processed
+type Result struct {
+	Changed []string
+	Failed  []string
+}
+
+// Run processes the staged content of the files matched by cfg.Match. A
+// fixed file is also written to the working tree if it has no unstaged
+// changes, so the working tree matches the commit. It returns an error if
+// git fails.
+func Run(cfg Config) (Result, error) {
+	var result Result
+	logf := func(format string, args ...interface{}) {
+		if cfg.Log != nil {
+			fmt.Fprintf(cfg.Log, format+"\n", args...)
+		}
+	}
+	top, err := git(cfg.Dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return result, err
+	}
+	root := strings.TrimSpace(string(top))
+	entries, err := stagedFiles(root)
+	if err != nil {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		if !cfg.Match(entry.path) {
+			continue
+		}
+		blob, err := git(root, nil, "cat-file", "blob", entry.object)
+		if err != nil {
+			return result, err
+		}
+		if bytes.IndexByte(blob, 0) >= 0 {
+			// Binary content is not text to process
+			continue
+		}
+		processed, err := cfg.Process(entry.path, string(blob))
+		if err != nil {
+			logf("%s: %v", entry.path, err)
+			result.Failed = append(result.Failed, entry.path)
+			continue
+		}
+		if processed == string(blob) {
+			continue
+		}
+		result.Changed = append(result.Changed, entry.path)
+		if cfg.Check {
+			logf("%s: needs processing", entry.path)
+			continue
+		}
+
+		object, err := git(root, strings.NewReader(processed), "hash-object", "-w", "--no-filters", "--stdin")
+		if err != nil {
+			return result, err
+		}
+		info := entry.mode + "," + strings.TrimSpace(string(object)) + "," + entry.path
+		if _, err := git(root, nil, "update-index", "--cacheinfo", info); err != nil {
+			return result, err
+		}
+		working := filepath.Join(root, filepath.FromSlash(entry.path))
+		if current, err := os.ReadFile(working); err == nil && bytes.Equal(current, blob) {
+			if err := os.WriteFile(working, []byte(processed), 0644); err != nil {
+				return result, err
+			}
+			logf("%s: processed", entry.path)
+		} else {
+			logf("%s: processed the staged content; the working tree has other changes and was left alone", entry.path)
+		}
+	}
+	return result, nil
+}
+
+// stagedFile is a regular file added or changed in the index
+type stagedFile struct {
+	mode, object, path string
+}
+
+// stagedFiles lists the regular files staged for the next commit with the
+// mode and object name of their staged content
+func stagedFiles(root string) ([]stagedFile, error) {
+	names, err := git(root, nil, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	staged := make(map[string]bool)
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name != "" {
+			staged[name] = true
+		}
+	}
+	if len(staged) == 0 {
+		return nil, nil
+	}
+
+	index, err := git(root, nil, "ls-files", "--stage", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []stagedFile
+	for _, line := range strings.Split(string(index), "\x00") {
+		// Each entry is "mode object stage\tpath"
+		info, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 || !staged[path] || fields[2] != "0" {
+			continue
+		}
+		if fields[0] == "100644" || fields[0] == "100755" {
+			files = append(files, stagedFile{mode: fields[0], object: fields[1], path: path})
+		}
+	}
+	return files, nil
+}
+
+// git runs a git command in dir and returns its output. The error includes
+// what git printed on stderr.
+func git(dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return output, nil
+}
@@ -0,0 +1,164 @@
+// Package httpapi serves the processors over HTTP so other services can
+// process text without running the CLI.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults for a zero Config
+const (
+	DefaultMaxBodyBytes = 1 << 20
+	DefaultTimeout      = 10 * time.Second
+)
+
+// Config limits the requests the handler accepts
+type Config struct {
+	// MaxBodyBytes is the largest request body accepted
+	MaxBodyBytes int64
+	// Timeout bounds how long a client waits for a reply. Processing
+	// cannot be interrupted, so a request that times out keeps its
+	// goroutine busy until its text is processed; MaxBodyBytes is what
+	// bounds that work.
+	Timeout time.Duration
+}
+
+// processRequest is the JSON body of POST /v1/process
+type processRequest struct {
+	processor.Request
+	Explain bool `json:"explain"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns the API handler:
+//
+//	POST /v1/process  process text, see processRequest
+//	GET  /healthz     report that the server is up
+func NewHandler(cfg Config) http.Handler {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	mux := http.NewServeMux()
+	process := http.TimeoutHandler(processHandler(cfg), cfg.Timeout, `{"error":"request timed out"}`)
+	mux.Handle("POST /v1/process", process)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// processHandler accepts either a JSON processRequest, or plain text with
+// the options in the query string. Plain text is answered with plain text
+// unless explain is set or the client accepts only JSON.
+func processHandler(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+
+		req, asJSON, status, err := decodeRequest(r)
+		if err != nil {
+			writeJSON(w, status, errorResponse{Error: err.Error()})
+			return
+		}
+		if req.Explain {
+			explanation, err := req.Request.Explain()
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, explanation)
+			return
+		}
+		result, err := req.Process()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		if !asJSON {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, result.Output)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// decodeRequest reads the request and reports whether to answer with JSON.
+// On error it also returns the status to answer with.
+func decodeRequest(r *http.Request) (processRequest, bool, int, error) {
+	var req processRequest
+	mediaType := "text/plain"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return req, true, http.StatusUnsupportedMediaType, fmt.Errorf("invalid Content-Type %q", contentType)
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			return req, true, bodyErrorStatus(err), fmt.Errorf("invalid request body: %v", err)
+		}
+		return req, true, 0, nil
+	case "text/plain":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return req, true, bodyErrorStatus(err), fmt.Errorf("reading request body: %v", err)
+		}
+		query := r.URL.Query()
+		req.Request = processor.Request{
+			Text: string(body),
+			Mode: query.Get("mode"),
+			RequestOptions: processor.RequestOptions{
+				Scope:          query.Get("scope"),
+				Whitespace:     query.Get("whitespace"),
+				Locale:         query.Get("locale"),
+				UnknownMarkers: query.Get("unknown-markers"),
+			},
+		}
+		if query.Has("rules") {
+			if req.Rules, err = processor.ParseRules(query.Get("rules")); err != nil {
+				return req, true, http.StatusBadRequest, err
+			}
+		}
+		if explain := query.Get("explain"); explain != "" {
+			if req.Explain, err = strconv.ParseBool(explain); err != nil {
+				return req, true, http.StatusBadRequest, fmt.Errorf("invalid explain %q. Use true or false", explain)
+			}
+		}
+		return req, req.Explain || r.Header.Get("Accept") == "application/json", 0, nil
+	}
+	return req, true, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported Content-Type %q. Use application/json or text/plain", mediaType)
+}
+
+// bodyErrorStatus tells an oversized body from a malformed one
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,177 @@
+package lsp
+
+import (
+	"errors"
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/tokenizer"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open text document
+type document struct {
+	text       string
+	lineStarts []int // byte offset of the start of each line
+	markers    []markerInfo
+}
+
+// markerInfo is a parenthesised span of the document and what it means
+type markerInfo struct {
+	tokenizer.MarkerSpan
+	marker rules.Marker
+	// err is a *rules.MarkerError for malformed or unknown markers
+	err error
+	// problem explains why a well-formed marker has no effect, or is ""
+	problem string
+	// attached is set when the marker directly follows a word
+	attached bool
+	// expansion is what a user-defined marker stands for, or ""
+	expansion string
+}
+
+// newDocument indexes text and finds its markers with the tokenizer. The
+// markers in definitions are known besides the built-in ones.
+func newDocument(text string, definitions rules.MarkerDefinitions) *document {
+	doc := &document{text: text, lineStarts: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			doc.lineStarts = append(doc.lineStarts, i+1)
+		}
+	}
+
+	tok := tokenizer.NewTokenizer()
+	var placed []rules.PlacedMarker
+	for _, span := range tok.FindMarkers(tok.Tokenize(text)) {
+		if expansion, defined := definitions.Expand(span.Content); defined {
+			// Defined markers become built-in ones before any rule runs,
+			// so they have no placement of their own to check
+			doc.markers = append(doc.markers, markerInfo{MarkerSpan: span, expansion: expansion})
+			placed = append(placed, rules.PlacedMarker{})
+			continue
+		}
+		marker, err := rules.ParseMarker(span.Content)
+		if errors.Is(err, rules.ErrNotMarker) {
+			continue
+		}
+		info := markerInfo{MarkerSpan: span, marker: marker, err: err}
+		if err == nil {
+			info.attached = span.Start > 0 && !isSpace(text[span.Start-1])
+		}
+		doc.markers = append(doc.markers, info)
+		placed = append(placed, rules.PlacedMarker{Marker: marker, Start: span.Start, End: span.End})
+	}
+	for i, problem := range rules.MarkerProblems(text, placed) {
+		doc.markers[i].problem = problem
+	}
+	return doc
+}
+
+// markerAt returns the marker containing offset, or nil
+func (d *document) markerAt(offset int) *markerInfo {
+	for i := range d.markers {
+		if d.markers[i].Start <= offset && offset < d.markers[i].End {
+			return &d.markers[i]
+		}
+	}
+	return nil
+}
+
+// position converts a byte offset to a position counting UTF-16 code units
+func (d *document) position(offset int) position {
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	character := 0
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		character += utf16.RuneLen(r)
+	}
+	return position{Line: line, Character: character}
+}
+
+// offset converts a position to a byte offset, clamping it to the document
+func (d *document) offset(pos position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[pos.Line]
+	for units := 0; offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		units += utf16.RuneLen(r)
+		if units > pos.Character {
+			break
+		}
+		offset += size
+	}
+	return offset
+}
+
+func (d *document) rangeOf(start, end int) lspRange {
+	return lspRange{Start: d.position(start), End: d.position(end)}
+}
+
+// application is the effect of a marker: the text from start to the end of
+// the marker becomes replacement
+type application struct {
+	start       int
+	original    string
+	replacement string
+}
+
+// apply works out what a well-formed backward marker does to the words
+// before it. Other markers between those words are kept as they are.
+func (d *document) apply(info *markerInfo) application {
+	markerStarts := make(map[int]int, len(d.markers))
+	for _, m := range d.markers {
+		markerStarts[m.End] = m.Start
+	}
+
+	// Walk back over the spacing and then over the words the marker takes
+	start := info.Start
+	for start > 0 && isSpace(d.text[start-1]) {
+		start--
+	}
+	wordsEnd := start
+	for n := 0; n < info.marker.Count && start > 0; {
+		for start > 0 && isSpace(d.text[start-1]) {
+			start--
+		}
+		if markerStart, ok := markerStarts[start]; ok {
+			start = markerStart
+			continue
+		}
+		for start > 0 && !isSpace(d.text[start-1]) {
+			start--
+		}
+		n++
+	}
+
+	var result strings.Builder
+	for i := start; i < wordsEnd; {
+		j := i
+		switch {
+		case isSpace(d.text[i]):
+			for j < wordsEnd && isSpace(d.text[j]) {
+				j++
+			}
+			result.WriteString(d.text[i:j])
+		case d.markerAt(i) != nil && d.markerAt(i).Start == i:
+			j = d.markerAt(i).End
+			result.WriteString(d.text[i:j])
+		default:
+			for j < wordsEnd && !isSpace(d.text[j]) {
+				j++
+			}
+			transformed, _ := rules.TransformWord(info.marker.Name, d.text[i:j])
+			result.WriteString(transformed)
+		}
+		i = j
+	}
+	return application{start: start, original: d.text[start:wordsEnd], replacement: result.String()}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
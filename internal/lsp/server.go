@@ -0,0 +1,377 @@
+// Package lsp implements a Language Server Protocol server for text with
+// go-reloaded markers. It reports malformed and unknown markers, previews
+// markers on hover, applies them through code actions and completes marker
+// names.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Server is a language server speaking JSON-RPC over a pair of streams
+type Server struct {
+	out       io.Writer
+	documents map[string]*document
+	// settings holds the settings of each open document, loaded from its
+	// config file when it is opened
+	settings map[string]*documentSettings
+	shutdown bool
+}
+
+// documentSettings are the settings of the config file that applies to a
+// document, and the processor they make. err is set, and proc nil, when the
+// config file cannot be used.
+type documentSettings struct {
+	settings config.Settings
+	proc     processor.Processor
+	err      error
+}
+
+// NewServer creates a language server writing responses to out
+func NewServer(out io.Writer) *Server {
+	return &Server{out: out, documents: make(map[string]*document), settings: make(map[string]*documentSettings)}
+}
+
+// Serve handles messages from in until the client sends exit or in ends
+func (s *Server) Serve(in io.Reader) error {
+	reader := bufio.NewReader(in)
+	for {
+		req, err := readMessage(reader)
+		var parseErr *parseError
+		switch {
+		case err == io.EOF:
+			return nil
+		case errors.As(err, &parseErr):
+			if err := s.replyError(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return err
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a request or notification
+func (s *Server) handle(req *request) error {
+	var result interface{}
+	var err error
+	switch req.Method {
+	case "initialize":
+		result = s.initialize()
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			// Reopening a document picks up changes to its config file
+			delete(s.settings, params.TextDocument.URI)
+			return s.open(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err = json.Unmarshal(req.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			// Full sync: the last change holds the whole text
+			return s.open(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			delete(s.documents, params.TextDocument.URI)
+			delete(s.settings, params.TextDocument.URI)
+			return s.publish(params.TextDocument.URI, nil)
+		}
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			result = s.hover(params)
+		}
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			result = s.codeActions(params)
+		}
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			result = s.completion(params)
+		}
+	default:
+		if req.ID == nil {
+			// Notifications the server does not use are ignored
+			return nil
+		}
+		return s.replyError(req.ID, codeMethodNotFound, fmt.Sprintf("method %q not found", req.Method))
+	}
+
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return s.replyError(req.ID, codeInvalidParams, err.Error())
+	}
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, message string) error {
+	return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: responseError{Code: code, Message: message}})
+}
+
+func (s *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":   1, // full
+			"hoverProvider":      true,
+			"codeActionProvider": true,
+			"completionProvider": map[string]interface{}{"triggerCharacters": []string{"("}},
+		},
+		"serverInfo": map[string]string{"name": "go-reloaded"},
+	}
+}
+
+// loadSettings finds the config file for the document at uri, as the CLI
+// does for an input file. Documents that are not files get the defaults.
+func loadSettings(uri string) *documentSettings {
+	var settings config.Settings
+	if parsed, err := url.Parse(uri); err == nil && parsed.Scheme == "file" {
+		path := filepath.FromSlash(parsed.Path)
+		cfg, err := config.Discover(filepath.Dir(path))
+		if err != nil {
+			return &documentSettings{err: err}
+		}
+		if cfg != nil {
+			settings = cfg.For(path)
+		}
+	}
+	mode := settings.Mode
+	if mode == "" {
+		mode = "pipeline"
+	}
+	opts, err := settings.Options()
+	if err != nil {
+		return &documentSettings{err: err}
+	}
+	proc, err := processor.New(mode, opts...)
+	if err != nil {
+		return &documentSettings{err: err}
+	}
+	return &documentSettings{settings: settings, proc: proc}
+}
+
+// open stores the text of a document and publishes its diagnostics
+func (s *Server) open(uri, text string) error {
+	settings := s.settings[uri]
+	if settings == nil {
+		settings = loadSettings(uri)
+		s.settings[uri] = settings
+	}
+	doc := newDocument(text, settings.settings.Markers)
+	s.documents[uri] = doc
+
+	diagnostics := []diagnostic{}
+	if settings.err != nil {
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    doc.rangeOf(0, 0),
+			Severity: severityError,
+			Source:   "go-reloaded",
+			Message:  fmt.Sprintf("cannot use the config file: %v", settings.err),
+		})
+	}
+	for _, info := range doc.markers {
+		d := diagnostic{Range: doc.rangeOf(info.Start, info.End), Source: "go-reloaded"}
+		var markerErr *rules.MarkerError
+		switch {
+		case errors.As(info.err, &markerErr):
+			d.Message = markerErr.Error()
+			d.Severity = severityError
+			if markerErr.Unknown {
+				d.Severity = severityWarning
+			}
+		case info.problem != "":
+			d.Message = info.problem
+			d.Severity = severityWarning
+		default:
+			continue
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return s.publish(uri, diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []diagnostic{}
+	}
+	return writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+}
+
+// hover previews the marker under the cursor
+func (s *Server) hover(params textDocumentPositionParams) interface{} {
+	doc := s.documents[params.TextDocument.URI]
+	if doc == nil {
+		return nil
+	}
+	info := doc.markerAt(doc.offset(params.Position))
+	if info == nil {
+		return nil
+	}
+
+	var value string
+	switch {
+	case info.expansion != "":
+		value = fmt.Sprintf("**(%s)** is defined as `%s`", info.Content, info.expansion)
+	case info.err != nil:
+		value = info.err.Error()
+	case info.problem != "":
+		value = info.problem
+	default:
+		value = fmt.Sprintf("**%s** %s", info.marker, describe(info.marker))
+		if info.marker.Direction != rules.Backward {
+			break
+		}
+		if app := doc.apply(info); app.original != "" {
+			value += fmt.Sprintf("\n\n`%s` → `%s`", app.original, app.replacement)
+		}
+	}
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    doc.rangeOf(info.Start, info.End),
+	}
+}
+
+// describe explains what marker does
+func describe(marker rules.Marker) string {
+	what := rules.MarkerDescriptions[marker.Name]
+	switch marker.Direction {
+	case rules.Forward:
+		if !marker.Numbered {
+			return what + " the word after it"
+		}
+		return fmt.Sprintf("%s the %d words after it", what, marker.Count)
+	case rules.RangeBegin:
+		return fmt.Sprintf("%s the words up to (%s:end)", what, marker.Name)
+	case rules.RangeEnd:
+		return fmt.Sprintf("%s the words from (%s:begin)", what, marker.Name)
+	}
+	switch {
+	case !marker.Numbered:
+		return what + " the word before it"
+	case marker.Count == 1:
+		return what + " the 1 word before it"
+	}
+	return fmt.Sprintf("%s the %d words before it", what, marker.Count)
+}
+
+// codeActions offers to apply or fix the markers in the requested range, and
+// to apply every marker in the document
+func (s *Server) codeActions(params codeActionParams) interface{} {
+	uri := params.TextDocument.URI
+	doc := s.documents[uri]
+	actions := []codeAction{}
+	if doc == nil {
+		return actions
+	}
+	edit := func(title, kind string, start, end int, newText string) {
+		actions = append(actions, codeAction{
+			Title: title,
+			Kind:  kind,
+			Edit: workspaceEdit{Changes: map[string][]textEdit{
+				uri: {{Range: doc.rangeOf(start, end), NewText: newText}},
+			}},
+		})
+	}
+
+	from, to := doc.offset(params.Range.Start), doc.offset(params.Range.End)
+	for i := range doc.markers {
+		info := &doc.markers[i]
+		if info.End < from || info.Start > to {
+			continue
+		}
+		var markerErr *rules.MarkerError
+		switch {
+		case info.expansion != "":
+			continue
+		case errors.As(info.err, &markerErr):
+			if markerErr.Suggestion != "" {
+				edit("Replace with "+markerErr.Suggestion, "quickfix", info.Start, info.End, markerErr.Suggestion)
+			}
+		case info.attached:
+			edit("Insert a space before "+info.marker.String(), "quickfix", info.Start, info.Start, " ")
+		case info.problem == "" && info.marker.Direction == rules.Backward:
+			app := doc.apply(info)
+			edit("Apply "+info.marker.String(), "refactor.rewrite", app.start, info.End, app.replacement)
+		}
+	}
+
+	// Apply every marker as the CLI would, with the settings of the
+	// document's config file
+	if settings := s.settings[uri]; len(doc.markers) > 0 && settings != nil && settings.proc != nil {
+		edit("Apply all markers", "source", 0, len(doc.text), settings.proc.Process(doc.text))
+	}
+	return actions
+}
+
+// completion offers marker names after an opening parenthesis
+func (s *Server) completion(params textDocumentPositionParams) interface{} {
+	items := []completionItem{}
+	doc := s.documents[params.TextDocument.URI]
+	if doc == nil {
+		return items
+	}
+
+	// Only complete a name being typed straight after "("
+	offset := doc.offset(params.Position)
+	start := offset
+	for start > 0 && isASCIILetter(doc.text[start-1]) {
+		start--
+	}
+	if start == 0 || doc.text[start-1] != '(' {
+		return items
+	}
+
+	prefix := doc.text[start:offset]
+	for _, name := range rules.MarkerNames {
+		if strings.HasPrefix(name, prefix) {
+			items = append(items, completionItem{
+				Label:         name,
+				Kind:          completionKeyword,
+				Detail:        "(" + name + ") " + rules.MarkerDescriptions[name] + " the word before it",
+				Documentation: numberedHint(name),
+			})
+		}
+	}
+	return items
+}
+
+// numberedHint documents the numbered form of case markers
+func numberedHint(name string) string {
+	if !rules.TakesCount(name) {
+		return ""
+	}
+	return fmt.Sprintf("Write (%s, n) to apply it to the n words before it, (%s>, n) for the n words after it, or (%s:begin) and (%s:end) around a span.", name, name, name, name)
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
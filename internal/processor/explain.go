@@ -0,0 +1,41 @@
+package processor
+
+import "go-reloaded/internal/rules"
+
+// Step records a rule that changed the text, with the whole text before and
+// after it
+type Step struct {
+	Rule   string `json:"rule"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Explanation is the result of processing a text together with the steps
+// that produced it
+type Explanation struct {
+	Output      string             `json:"output"`
+	Steps       []Step             `json:"steps"`
+	Diagnostics []rules.Diagnostic `json:"diagnostics"`
+}
+
+// ExplainProcessor is a DiagnosticProcessor that can also report which of
+// its rules changed the text, in the order they ran
+type ExplainProcessor interface {
+	DiagnosticProcessor
+	Explain(text string) Explanation
+}
+
+// explainer collects steps. A nil explainer records nothing, so processors
+// share one code path whether or not they are explaining.
+type explainer struct {
+	steps []Step
+}
+
+// record notes rule as a step if it changed before into after, and returns
+// after
+func (e *explainer) record(rule, before, after string) string {
+	if e != nil && before != after {
+		e.steps = append(e.steps, Step{Rule: rule, Before: before, After: after})
+	}
+	return after
+}
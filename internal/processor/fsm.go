@@ -2,7 +2,6 @@ package processor
 
 import (
 	"go-reloaded/internal/rules"
-	"strconv"
 	"strings"
 )
 
@@ -15,177 +14,255 @@ const (
 	InQuotes
 )
 
-// FSM implements the Processor interface using finite state machine
+// FSM implements the Processor interface using finite state machine. The
+// state lives in each call, so one FSM can process texts concurrently.
 type FSM struct {
-	state FSMState
+	cfg config
 }
 
 // NewFSM creates a new FSM processor
-func NewFSM() *FSM {
-	return &FSM{state: Normal}
+func NewFSM(opts ...Option) *FSM {
+	return &FSM{cfg: newConfig(opts)}
 }
 
 // Process applies rules using FSM approach with character-by-character processing
 func (f *FSM) Process(text string) string {
-	// FSM processes text character by character, tracking state
-	result := f.processWithFSM(text)
-	
-	// Apply articles last to avoid conflicts
-	result = rules.FixArticles(result)
+	result, _ := f.ProcessWithDiagnostics(text)
 	return result
 }
 
+// ProcessWithDiagnostics applies rules using the FSM approach and reports
+// any problems found
+func (f *FSM) ProcessWithDiagnostics(text string) (string, []rules.Diagnostic) {
+	return f.process(text, nil)
+}
+
+// Explain applies rules using the FSM approach and reports the steps that
+// changed the text. The state machine applies all markers in one step.
+func (f *FSM) Explain(text string) Explanation {
+	var e explainer
+	output, diagnostics := f.process(text, &e)
+	return Explanation{Output: output, Steps: e.steps, Diagnostics: diagnostics}
+}
+
+// normalizeWhitespace applies the whitespace policy to text, as the
+// whitespace rule does
+func (f *FSM) normalizeWhitespace(text string) string {
+	return f.cfg.normalizeWhitespace(text)
+}
+
+func (f *FSM) process(text string, e *explainer) (string, []rules.Diagnostic) {
+	result := expandMarkers(text, f.cfg, e)
+	result, found := checkMarkers(result, f.cfg, e)
+	
+	// FSM processes text character by character, tracking state, and applies
+	// case and number markers in one pass
+	var diagnostics []rules.Diagnostic
+	markersApplied := false
+	for _, name := range f.cfg.enabledRules() {
+		switch name {
+		case "case", "numbers":
+			if !markersApplied {
+				result, diagnostics = f.processWithFSM(result, e)
+				markersApplied = true
+			}
+		case "quotes":
+			result = e.record(name, result, rules.CleanQuotesWithOptions(result, f.cfg.rules))
+		case "punctuation":
+			result = e.record(name, result, rules.FixPunctuationWithOptions(result, f.cfg.rules))
+		case "articles":
+			result = e.record(name, result, rules.FixArticlesWithOptions(result, f.cfg.rules))
+		case "whitespace":
+			result = e.record(name, result, rules.NormalizeWhitespace(result, f.cfg.rules.Whitespace))
+		}
+	}
+	return result, rules.SortDiagnostics(append(found, diagnostics...))
+}
+
 // processWithFSM uses finite state machine to process text character by character
-func (f *FSM) processWithFSM(text string) string {
-	var result strings.Builder
-	var currentWord strings.Builder
+func (f *FSM) processWithFSM(text string, e *explainer) (string, []rules.Diagnostic) {
+	var result rules.WordBuffer
+	var pending strings.Builder
 	var markerContent strings.Builder
+	var diagnostics []rules.Diagnostic
+	var lines *rules.LineIndex
+	warn := func(offset int, message string) {
+		if lines == nil {
+			lines = rules.NewLineIndex(text)
+		}
+		diagnostics = append(diagnostics, lines.Diagnostic(offset, rules.Warning, "%s", message))
+	}
+	waiting := rules.CaseMarkers{Buffer: &result, Scope: f.cfg.rules.Scope, Warn: warn}
+	directional := f.directionalMarkers(text)
+	markerStart, skipUntil := 0, 0
 	
-	f.state = Normal
-	runes := []rune(text)
+	state := Normal
 	
-	for _, char := range runes {
-		switch f.state {
+	for i, char := range text {
+		if i < skipUntil {
+			// Spacing dropped with a forward or range marker
+			continue
+		}
+		switch state {
 		case Normal:
 			if char == '(' {
-				// Save current word and enter marker state
-				if currentWord.Len() > 0 {
-					result.WriteString(currentWord.String())
-					currentWord.Reset()
-				}
-				f.state = InMarker
+				// Enter marker state; text so far becomes the marker's target
+				state = InMarker
 				markerContent.Reset()
-			} else if char == '\'' {
-				// Handle quotes
-				if currentWord.Len() > 0 {
-					result.WriteString(currentWord.String())
-					currentWord.Reset()
-				}
-				f.state = InQuotes
-				result.WriteRune(char)
-			} else if char == ' ' || char == '\t' || char == '\n' {
-				// Word boundary
-				if currentWord.Len() > 0 {
-					result.WriteString(currentWord.String())
-					currentWord.Reset()
-				}
-				result.WriteRune(char)
+				markerStart = i
 			} else {
-				currentWord.WriteRune(char)
+				pending.WriteRune(char)
 			}
 			
 		case InMarker:
 			if char == ')' {
-				// Process the marker and previous word
-				marker := markerContent.String()
-				f.state = Normal
+				// Process the marker and previous words
+				state = Normal
+				result.Write(pending.String())
+				pending.Reset()
+				waiting.Flush(false)
 				
-				// Apply transformation based on marker
-				prevText := result.String()
-				transformedText := f.applyMarkerTransformation(prevText, marker)
-				result.Reset()
-				result.WriteString(transformedText)
+				marker, ok := directional[markerStart]
+				after := rules.LeadingSpace(text[i+1:])
+				switch {
+				case !ok:
+					placed := rules.ClosesBackward(text, markerStart, i+1)
+					skip, warning := f.applyMarkerTransformation(&result, markerContent.String(), placed, after)
+					if warning != "" {
+						warn(markerStart, warning)
+					}
+					if skip {
+						skipUntil = i + 1 + len(after)
+					}
+				case marker.Direction == rules.Forward:
+					if waiting.Forward(marker, markerStart, after) {
+						skipUntil = i + 1 + len(after)
+					}
+				case marker.Direction == rules.RangeBegin:
+					if waiting.Begin(marker, markerStart, after) {
+						skipUntil = i + 1 + len(after)
+					}
+				default:
+					if waiting.End(marker, after) {
+						skipUntil = i + 1 + len(after)
+					}
+				}
+			} else if char == '(' {
+				// As in the tokenizer, only the last "(" opens the marker
+				pending.WriteString("(" + markerContent.String())
+				markerContent.Reset()
+				markerStart = i
 			} else {
 				markerContent.WriteRune(char)
 			}
-			
-		case InQuotes:
-			result.WriteRune(char)
-			if char == '\'' {
-				f.state = Normal
-			}
 		}
 	}
 	
-	// Add any remaining word
-	if currentWord.Len() > 0 {
-		result.WriteString(currentWord.String())
+	// Add any remaining text, including an unclosed marker
+	result.Write(pending.String())
+	if state == InMarker {
+		result.Write("(" + markerContent.String())
 	}
+	waiting.Flush(true)
 	
-	// Clean up quotes and punctuation
-	finalResult := result.String()
-	finalResult = rules.CleanQuotes(finalResult)
-	finalResult = rules.FixPunctuation(finalResult)
-	
-	return finalResult
+	return e.record("markers", text, result.String()), diagnostics
 }
 
-// applyMarkerTransformation applies transformations based on markers
-func (f *FSM) applyMarkerTransformation(text, marker string) string {
-	words := strings.Fields(text)
-	if len(words) == 0 {
-		return text
+// directionalMarkers finds the forward and range markers the state machine
+// will meet in text that can apply where they are, keyed by the offset of
+// their "(". Range markers are only included in pairs.
+func (f *FSM) directionalMarkers(text string) map[int]rules.Marker {
+	if !f.cfg.enabled("case") || !strings.ContainsAny(text, ">:") {
+		return nil
 	}
-	
-	// Handle hex conversion
-	if marker == "hex" {
-		lastWord := words[len(words)-1]
-		if val, err := strconv.ParseInt(lastWord, 16, 64); err == nil {
-			words[len(words)-1] = strconv.FormatInt(val, 10)
+	var markers []rules.Marker
+	var starts []int
+	state, start := Normal, 0
+	for i, char := range text {
+		switch {
+		case char == '(':
+			state, start = InMarker, i
+		case state == InMarker && char == ')':
+			state = Normal
+			marker, err := rules.ParseMarker(text[start+1 : i])
+			switch {
+			case err != nil || marker.Direction == rules.Backward:
+				continue
+			case marker.Direction == rules.RangeEnd:
+				if !rules.ClosesBackward(text, start, i+1) {
+					continue
+				}
+			case !rules.OpensForward(text, start, i+1):
+				continue
+			}
+			markers = append(markers, marker)
+			starts = append(starts, start)
 		}
-		return strings.Join(words, " ")
 	}
 	
-	// Handle bin conversion
-	if marker == "bin" {
-		lastWord := words[len(words)-1]
-		if val, err := strconv.ParseInt(lastWord, 2, 64); err == nil {
-			words[len(words)-1] = strconv.FormatInt(val, 10)
+	found := make(map[int]rules.Marker, len(markers))
+	for i, partner := range rules.MatchRanges(markers) {
+		if partner >= 0 || markers[i].Direction == rules.Forward {
+			found[starts[i]] = markers[i]
 		}
-		return strings.Join(words, " ")
 	}
-	
-	// Handle case transformations
-	if marker == "up" {
-		if len(words) > 0 {
-			words[len(words)-1] = strings.ToUpper(words[len(words)-1])
-		}
-		return strings.Join(words, " ")
+	return found
+}
+
+// applyMarkerTransformation applies the marker to the last words in result.
+// Recognised markers also drop the whitespace on one side of them, as
+// rules.WordBuffer.DropSpace does with after, the whitespace after the
+// marker in the text; anything rules.ParseMarker rejects, and markers that
+// cannot apply because they are not placed apart from the words around
+// them or follow an invalid number, are kept as text, as the pipeline does.
+// placed tells whether the marker is apart from the words around it. It
+// reports whether to skip the whitespace after the marker, and returns a
+// warning if a numbered marker had to be clamped to its scope.
+func (f *FSM) applyMarkerTransformation(result *rules.WordBuffer, marker string, placed bool, after string) (bool, string) {
+	parsed, err := rules.ParseMarker(marker)
+	if err != nil || parsed.Direction != rules.Backward || !f.cfg.enabled(markerRule(parsed)) || !placed || result.WordCount() == 0 {
+		// Not a marker, its rule is off or it has no word to apply to, so
+		// keep the parenthesised text as it was
+		result.Write("(" + marker + ")")
+		return false, ""
 	}
-	
-	if marker == "low" {
-		if len(words) > 0 {
-			words[len(words)-1] = strings.ToLower(words[len(words)-1])
+
+	if !rules.TakesCount(parsed.Name) {
+		converted := false
+		if !result.EndsWithWord() {
+			// A number marker converts the word right before it
+			result.Write("(" + marker + ")")
+			return false, ""
 		}
-		return strings.Join(words, " ")
-	}
-	
-	if marker == "cap" {
-		if len(words) > 0 {
-			lastWord := words[len(words)-1]
-			// Don't override already uppercase words
-			if lastWord != strings.ToUpper(lastWord) || len(lastWord) == 1 {
-				words[len(words)-1] = strings.Title(strings.ToLower(lastWord))
-			}
+		result.TransformLast(1, func(word string) string {
+			word, converted = rules.ConvertLastNumber(parsed.Name, word)
+			return word
+		})
+		if !converted {
+			result.Write("(" + marker + ")")
+			return false, ""
 		}
-		return strings.Join(words, " ")
+		skip := result.DropSpace(after)
+		result.EndWord()
+		return skip, ""
 	}
 	
-	// Handle numbered transformations (simplified)
-	if strings.Contains(marker, ",") {
-		parts := strings.Split(marker, ",")
-		if len(parts) == 2 {
-			cmd := strings.TrimSpace(parts[0])
-			nStr := strings.TrimSpace(parts[1])
-			if n, err := strconv.Atoi(nStr); err == nil && n > 0 {
-				if n <= len(words) {
-					for i := len(words) - n; i < len(words); i++ {
-						switch cmd {
-						case "up":
-							words[i] = strings.ToUpper(words[i])
-						case "low":
-							words[i] = strings.ToLower(words[i])
-						case "cap":
-							if words[i] != strings.ToUpper(words[i]) || len(words[i]) == 1 {
-								words[i] = strings.Title(strings.ToLower(words[i]))
-							}
-						}
-					}
-				}
-			}
+	// Handle numbered transformations like "up, 2"
+	n := parsed.Count
+	var warning string
+	if parsed.Numbered {
+		n, warning = result.ClampToScope("("+marker+")", n, f.cfg.rules.Scope)
+		if n == 0 {
+			// Nothing in scope, so the marker stays where it is
+			result.Write("(" + marker + ")")
+			return false, warning
 		}
-		return strings.Join(words, " ")
 	}
-	
-	return text
-}
\ No newline at end of file
+	skip := result.DropSpace(after)
+	result.TransformLast(n, func(word string) string {
+		transformed, _ := rules.TransformWord(parsed.Name, word)
+		return transformed
+	})
+	result.EndWord()
+	return skip, warning
+}
@@ -6,28 +6,59 @@ import (
 )
 
 // Hybrid implements the Processor interface using FSM tokenizer + pipeline rules
-type Hybrid struct{}
+type Hybrid struct {
+	cfg config
+}
 
 // NewHybrid creates a new hybrid processor
-func NewHybrid() *Hybrid {
-	return &Hybrid{}
+func NewHybrid(opts ...Option) *Hybrid {
+	return &Hybrid{cfg: newConfig(opts)}
 }
 
 // Process applies rules using hybrid approach: FSM tokenizer + pipeline rules
 func (h *Hybrid) Process(text string) string {
-	// Step 1: Use FSM tokenizer to parse and preprocess the text
+	result, _ := h.ProcessWithDiagnostics(text)
+	return result
+}
+
+// ProcessWithDiagnostics applies rules using the hybrid approach and reports
+// any problems found
+func (h *Hybrid) ProcessWithDiagnostics(text string) (string, []rules.Diagnostic) {
+	return h.process(text, nil)
+}
+
+// Explain applies rules using the hybrid approach and reports the steps that
+// changed the text
+func (h *Hybrid) Explain(text string) Explanation {
+	var e explainer
+	output, diagnostics := h.process(text, &e)
+	return Explanation{Output: output, Steps: e.steps, Diagnostics: diagnostics}
+}
+
+// normalizeWhitespace applies the whitespace policy to text, as the
+// whitespace rule does
+func (h *Hybrid) normalizeWhitespace(text string) string {
+	return h.cfg.normalizeWhitespace(text)
+}
+
+func (h *Hybrid) process(text string, e *explainer) (string, []rules.Diagnostic) {
+	// Step 1: Use FSM tokenizer to parse and preprocess the text, expanding
+	// user-defined markers
 	tokenizer := tokenizer.NewTokenizer()
 	tokens := tokenizer.Tokenize(text)
+	if len(h.cfg.rules.Markers) > 0 {
+		tokens = tokenizer.ExpandMarkers(tokens, h.cfg.rules.Markers)
+		text = e.record("definitions", text, tokenizer.Reconstruct(tokens))
+	}
+	checked, found := checkMarkers(text, h.cfg, e)
+	if checked != text {
+		text, tokens = checked, tokenizer.Tokenize(checked)
+	}
 	
 	// Step 2: Apply smart preprocessing based on token analysis
-	preprocessedText := tokenizer.PreprocessTokens(tokens)
+	preprocessedText := e.record("tokenizer", text, tokenizer.PreprocessTokens(tokens))
 	
 	// Step 3: Apply pipeline rules to the preprocessed text
-	result := rules.ApplyCase(preprocessedText)
-	result = rules.ApplyNumbers(result)
-	result = rules.CleanQuotes(result)
-	result = rules.FixPunctuation(result)
-	result = rules.FixArticles(result) // Apply articles last to avoid conflicts
-	
-	return result
-}
\ No newline at end of file
+	result, diagnostics := applyRules(preprocessedText, h.cfg, e)
+	return result, rules.SortDiagnostics(append(found, diagnostics...))
+}
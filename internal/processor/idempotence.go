@@ -0,0 +1,41 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VerifyIdempotent processes text, then processes the result a second time
+// and returns an error describing the first difference if the two disagree
+func VerifyIdempotent(p Processor, text string) (string, error) {
+	result := p.Process(text)
+	return result, CheckIdempotent(p, result)
+}
+
+// CheckIdempotent processes an already processed result again and returns an
+// error describing the first difference if it changes
+func CheckIdempotent(p Processor, result string) error {
+	again := p.Process(result)
+	if again == result {
+		return nil
+	}
+
+	// Find the first differing byte and report it as line:column
+	i := 0
+	for i < len(result) && i < len(again) && result[i] == again[i] {
+		i++
+	}
+	line := strings.Count(result[:i], "\n") + 1
+	column := i - strings.LastIndex(result[:i], "\n")
+	return fmt.Errorf("output is not idempotent at line %d, column %d: %q became %q",
+		line, column, excerpt(result, i), excerpt(again, i))
+}
+
+// excerpt returns a short piece of text starting at offset
+func excerpt(text string, offset int) string {
+	end := offset + 20
+	if end > len(text) {
+		end = len(text)
+	}
+	return text[offset:end]
+}
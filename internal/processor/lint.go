@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/tokenizer"
+	"sort"
+)
+
+// LintRule is a kind of problem Lint reports
+type LintRule struct {
+	ID          string
+	Description string
+}
+
+// LintRules lists the kinds of problem Lint reports, in the order they are
+// documented
+var LintRules = []LintRule{
+	{"articles", "an article that does not match the sound of the word after it"},
+	{"punctuation", "spacing around punctuation that the punctuation rule would change"},
+	{"quotes", "spacing inside quotes, or a quote mark without a partner"},
+	{"leftover-markers", "a marker that has not been applied"},
+	{"unknown-markers", "an unknown or malformed marker"},
+}
+
+// Finding is a problem found by Lint
+type Finding struct {
+	Rule string `json:"rule"`
+	rules.Diagnostic
+}
+
+// Lint reports the problems in text that the processors would fix, and the
+// markers they would apply or leave as text, without changing text. The
+// checks are those of the rules enabled in opts, plus the markers.
+func Lint(text string, opts ...Option) []Finding {
+	cfg := newConfig(opts)
+	var findings []Finding
+	add := func(rule string, diagnostics []rules.Diagnostic) {
+		for _, d := range diagnostics {
+			findings = append(findings, Finding{Rule: rule, Diagnostic: d})
+		}
+	}
+	for _, name := range cfg.enabledRules() {
+		switch name {
+		case "quotes":
+			add(name, rules.CheckQuotes(text, cfg.rules))
+		case "punctuation":
+			add(name, rules.CheckPunctuation(text, cfg.rules))
+		case "articles":
+			add(name, rules.CheckArticles(text, cfg.rules))
+		}
+	}
+	lintMarkers(text, cfg, add)
+
+	// Findings at one position stay in rule order
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings
+}
+
+// lintMarkers reports the markers in text: well-formed and defined markers
+// are left over from before processing, and the rest are unknown or
+// malformed
+func lintMarkers(text string, cfg config, add func(rule string, diagnostics []rules.Diagnostic)) {
+	tok := tokenizer.NewTokenizer()
+	lines := rules.NewLineIndex(text)
+	for _, span := range tok.FindMarkers(tok.Tokenize(text)) {
+		_, err := rules.ParseMarker(span.Content)
+		var markerErr *rules.MarkerError
+		_, defined := cfg.rules.Markers.Expand(span.Content)
+		switch {
+		case err == nil || defined:
+			message := fmt.Sprintf("marker (%s) has not been applied", span.Content)
+			add("leftover-markers", []rules.Diagnostic{lines.Diagnostic(span.Start, rules.Warning, "%s", message)})
+		case errors.As(err, &markerErr):
+			add("unknown-markers", []rules.Diagnostic{lines.Diagnostic(span.Start, rules.Error, "%v", markerErr)})
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"errors"
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/tokenizer"
+)
+
+// checkMarkers reports the unknown and malformed markers in text, and the
+// markers of enabled rules that cannot apply where they are, with the
+// severity of the marker policy, and strips them if the policy says to
+func checkMarkers(text string, cfg config, e *explainer) (string, []rules.Diagnostic) {
+	tok := tokenizer.NewTokenizer()
+	spans := tok.FindMarkers(tok.Tokenize(text))
+	messages := make([]string, len(spans))
+	placed := make([]rules.PlacedMarker, len(spans))
+	for i, span := range spans {
+		marker, err := rules.ParseMarker(span.Content)
+		var markerErr *rules.MarkerError
+		switch {
+		case errors.As(err, &markerErr):
+			messages[i] = markerErr.Error()
+		case err == nil && cfg.enabled(markerRule(marker)):
+			placed[i] = rules.PlacedMarker{Marker: marker, Start: span.Start, End: span.End}
+		}
+	}
+	for i, problem := range rules.MarkerProblems(text, placed) {
+		if problem != "" {
+			messages[i] = problem
+		}
+	}
+
+	var diagnostics []rules.Diagnostic
+	var problems [][2]int
+	var lines *rules.LineIndex
+	for i, span := range spans {
+		if messages[i] == "" {
+			continue
+		}
+		if lines == nil {
+			lines = rules.NewLineIndex(text)
+		}
+		diagnostics = append(diagnostics, lines.Diagnostic(span.Start, cfg.rules.MarkerPolicy.Severity(), "%s", messages[i]))
+		problems = append(problems, [2]int{span.Start, span.End})
+	}
+	if cfg.rules.MarkerPolicy != rules.MarkersStrip || len(problems) == 0 {
+		return text, diagnostics
+	}
+
+	return e.record("unknown markers", text, rules.RemoveMarkers(text, problems)), diagnostics
+}
+
+// markerRule returns the name of the rule that applies marker
+func markerRule(marker rules.Marker) string {
+	if rules.TakesCount(marker.Name) {
+		return "case"
+	}
+	return "numbers"
+}
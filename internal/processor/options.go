@@ -0,0 +1,107 @@
+package processor
+
+import "go-reloaded/internal/rules"
+
+// Option configures a processor
+type Option func(*config)
+
+// config holds the settings shared by all processors
+type config struct {
+	rules rules.Options
+	// ruleNames lists the enabled rules in order, or is nil for RuleNames
+	ruleNames []string
+}
+
+// newConfig applies opts to the default configuration
+func newConfig(opts []Option) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithScope limits how far back numbered markers like (up, 5) can reach
+func WithScope(scope rules.Scope) Option {
+	return func(cfg *config) {
+		cfg.rules.Scope = scope
+	}
+}
+
+// WithWhitespace sets the whitespace policy applied to the processed text
+func WithWhitespace(policy rules.Whitespace) Option {
+	return func(cfg *config) {
+		cfg.rules.Whitespace = policy
+	}
+}
+
+// WithLocale sets the punctuation and quote conventions of the processed text
+func WithLocale(locale rules.Locale) Option {
+	return func(cfg *config) {
+		cfg.rules.Locale = locale
+	}
+}
+
+// WithArticleExceptions adds words to the built-in exceptions of the
+// article rule
+func WithArticleExceptions(exceptions rules.ArticleExceptions) Option {
+	return func(cfg *config) {
+		cfg.rules.Articles = exceptions
+	}
+}
+
+// WithMarkers adds user-defined markers, given as a map from each name to
+// its definition. New rejects definitions that MarkerDefinitions.Validate
+// rejects.
+func WithMarkers(definitions map[string]string) Option {
+	return func(cfg *config) {
+		cfg.rules.Markers = definitions
+	}
+}
+
+// WithMarkerPolicy sets what happens to unknown and malformed markers like
+// (upp) or (cap, -2), and to markers of enabled rules that cannot apply where
+// they are. They are reported as diagnostics under every policy.
+func WithMarkerPolicy(policy rules.MarkerPolicy) Option {
+	return func(cfg *config) {
+		cfg.rules.MarkerPolicy = policy
+	}
+}
+
+// WithRules applies only the named rules from RuleNames, in the given order.
+// New rejects lists that ValidateRules rejects. The FSM applies case and
+// number markers in a single pass where the first of them is listed.
+func WithRules(names []string) Option {
+	return func(cfg *config) {
+		cfg.ruleNames = append([]string{}, names...)
+	}
+}
+
+// ParseOptions builds options from the names of a scope, whitespace policy
+// and locale, as given on a command line or in a request. Empty names keep
+// the defaults.
+func ParseOptions(scope, whitespace, locale string) ([]Option, error) {
+	var opts []Option
+	if scope != "" {
+		parsed, err := rules.ParseScope(scope)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithScope(parsed))
+	}
+	if whitespace != "" {
+		parsed, err := rules.ParseWhitespace(whitespace)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithWhitespace(parsed))
+	}
+	if locale != "" {
+		parsed, err := rules.ParseLocale(locale)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithLocale(parsed))
+	}
+	return opts, nil
+}
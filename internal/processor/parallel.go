@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"go-reloaded/internal/rules"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// paragraphSeparator matches one or more blank lines between paragraphs,
+// with the spaces and carriage return ending the line before them
+var paragraphSeparator = regexp.MustCompile(`[ \t]*\r?\n(?:[ \t\r]*\n)+`)
+
+// Parallel implements the Processor interface by splitting text on blank
+// lines and processing the paragraphs concurrently across a worker pool.
+// Each worker gets its own processor from newProcessor, and paragraphs are
+// reassembled in order with their separators, which get the whitespace
+// policy of the processors.
+//
+// Numbered markers like (up, 5) only see words in their own paragraph.
+// Every call gets its own processors, so a Parallel is safe for concurrent
+// use.
+type Parallel struct {
+	newProcessor func() Processor
+	workers      int
+}
+
+// NewParallel creates a parallel processor with the given number of workers.
+// A worker count of zero or less uses one worker per CPU.
+func NewParallel(newProcessor func() Processor, workers int) *Parallel {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &Parallel{newProcessor: newProcessor, workers: workers}
+}
+
+// paragraph is a piece of input together with the blank lines that follow it
+type paragraph struct {
+	text      string
+	separator string
+}
+
+// Process applies the wrapped processor to every paragraph
+func (p *Parallel) Process(text string) string {
+	result, _ := p.ProcessWithDiagnostics(text)
+	return result
+}
+
+// ProcessWithDiagnostics applies the wrapped processor to every paragraph
+// and collects the diagnostics of processors that report them, with line
+// numbers relative to the whole text
+func (p *Parallel) ProcessWithDiagnostics(text string) (string, []rules.Diagnostic) {
+	paragraphs := splitParagraphs(text)
+	results := make([]string, len(paragraphs))
+	diagnostics := make([][]rules.Diagnostic, len(paragraphs))
+
+	workers := p.workers
+	if workers > len(paragraphs) {
+		workers = len(paragraphs)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proc := p.newProcessor()
+			for i := range jobs {
+				if dp, ok := proc.(DiagnosticProcessor); ok {
+					results[i], diagnostics[i] = dp.ProcessWithDiagnostics(paragraphs[i].text)
+				} else {
+					results[i] = proc.Process(paragraphs[i].text)
+				}
+			}
+		}()
+	}
+	for i := range paragraphs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// The separators are whitespace, which sequential processing would
+	// normalise with the rest of the text
+	separator := func(text string) string { return text }
+	if ws, ok := p.newProcessor().(whitespaceNormalizer); ok {
+		separator = ws.normalizeWhitespace
+	}
+
+	var result strings.Builder
+	var all []rules.Diagnostic
+	result.Grow(len(text))
+	line := 0
+	for i, para := range paragraphs {
+		result.WriteString(results[i])
+		result.WriteString(separator(para.separator))
+		for _, d := range diagnostics[i] {
+			d.Line += line
+			all = append(all, d)
+		}
+		line += strings.Count(para.text, "\n") + strings.Count(para.separator, "\n")
+	}
+	return result.String(), all
+}
+
+// whitespaceNormalizer is implemented by processors that apply a whitespace
+// policy, so the text between paragraphs can get the same policy
+type whitespaceNormalizer interface {
+	normalizeWhitespace(text string) string
+}
+
+// splitParagraphs splits text at blank lines. A split is skipped when rules
+// could act across it, so processing the pieces separately gives the same
+// result as processing the whole text.
+func splitParagraphs(text string) []paragraph {
+	var paragraphs []paragraph
+	var current strings.Builder
+	last := 0
+	for _, m := range paragraphSeparator.FindAllStringIndex(text, -1) {
+		current.WriteString(text[last:m[0]])
+		if !safeToSplit(current.String(), text[m[1]:]) {
+			current.WriteString(text[m[0]:m[1]])
+			last = m[1]
+			continue
+		}
+		paragraphs = append(paragraphs, paragraph{text: current.String(), separator: text[m[0]:m[1]]})
+		current.Reset()
+		last = m[1]
+	}
+	current.WriteString(text[last:])
+	return append(paragraphs, paragraph{text: current.String()})
+}
+
+// safeToSplit reports whether before and after can be processed separately
+func safeToSplit(before, after string) bool {
+	// Quotes and markers must be closed before the split
+	if strings.Count(before, "'")%2 != 0 || strings.Count(before, "(") != strings.Count(before, ")") {
+		return false
+	}
+
+	// Forward and range markers apply to the words after them, and markers
+	// with no words before them drop whitespace depending on what follows
+	if rules.WaitsForWords(before) || !hasWords(before) {
+		return false
+	}
+	// So do markers starting a paragraph, and an article stays an article
+	// with markers after it
+	before, paragraphStart := trimMarkers(before)
+	if paragraphStart {
+		return false
+	}
+
+	// Punctuation and markers attach to the word before them
+	next, _ := utf8.DecodeRuneInString(strings.TrimLeft(after, " \t\r\n"))
+	if next == '(' || rules.PunctuationRules[next].Attach {
+		return false
+	}
+
+	// Articles depend on the word after them
+	start := len(before)
+	for start > 0 && isWordByte(before[start-1]) {
+		start--
+	}
+	switch before[start:] {
+	case "a", "A", "an", "AN":
+		return false
+	}
+	return true
+}
+
+// hasWords reports whether text has words other than markers
+func hasWords(text string) bool {
+	var words rules.WordBuffer
+	words.Write(text)
+	return words.WordCount() > 0
+}
+
+// trimMarkers removes the markers at the end of text, reporting whether
+// they start a paragraph. A removed marker drops the whitespace on the side
+// with fewer line breaks, so only then does it depend on what follows it.
+func trimMarkers(text string) (string, bool) {
+	text = strings.TrimRight(text, " \t\r\n")
+	for {
+		open := strings.LastIndexByte(text, '(')
+		if open < 0 || !strings.HasSuffix(text, ")") {
+			return text, false
+		}
+		if _, err := rules.ParseMarker(text[open+1 : len(text)-1]); err == rules.ErrNotMarker {
+			return text, false
+		}
+		rest := strings.TrimRight(text[:open], " \t\r\n")
+		space := text[len(rest):open]
+		if strings.Count(space, "\n") >= 2 {
+			return rest, true
+		}
+		text = rest
+		if space == "" {
+			return text, false
+		}
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
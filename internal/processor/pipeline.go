@@ -1,21 +1,82 @@
 package processor
 
-import "go-reloaded/internal/rules"
+import (
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/tokenizer"
+)
 
 // Pipeline implements the Processor interface using sequential rule application
-type Pipeline struct{}
+type Pipeline struct {
+	cfg config
+}
 
 // NewPipeline creates a new pipeline processor
-func NewPipeline() *Pipeline {
-	return &Pipeline{}
+func NewPipeline(opts ...Option) *Pipeline {
+	return &Pipeline{cfg: newConfig(opts)}
 }
 
 // Process applies all rules in the specified order
 func (p *Pipeline) Process(text string) string {
-	text = rules.ApplyCase(text)
-	text = rules.ApplyNumbers(text)
-	text = rules.CleanQuotes(text)
-	text = rules.FixPunctuation(text)
-	text = rules.FixArticles(text) // Apply articles last to avoid conflicts
+	text, _ = p.ProcessWithDiagnostics(text)
 	return text
-}
\ No newline at end of file
+}
+
+// ProcessWithDiagnostics applies all rules and reports any problems found
+func (p *Pipeline) ProcessWithDiagnostics(text string) (string, []rules.Diagnostic) {
+	return p.process(text, nil)
+}
+
+// Explain applies all rules and reports the ones that changed the text
+func (p *Pipeline) Explain(text string) Explanation {
+	var e explainer
+	output, diagnostics := p.process(text, &e)
+	return Explanation{Output: output, Steps: e.steps, Diagnostics: diagnostics}
+}
+
+// normalizeWhitespace applies the whitespace policy to text, as the
+// whitespace rule does
+func (p *Pipeline) normalizeWhitespace(text string) string {
+	return p.cfg.normalizeWhitespace(text)
+}
+
+func (p *Pipeline) process(text string, e *explainer) (string, []rules.Diagnostic) {
+	text = expandMarkers(text, p.cfg, e)
+	text, found := checkMarkers(text, p.cfg, e)
+	text, diagnostics := applyRules(text, p.cfg, e)
+	return text, rules.SortDiagnostics(append(found, diagnostics...))
+}
+
+// expandMarkers replaces the markers defined with WithMarkers by what they
+// stand for, using the tokenizer to find them
+func expandMarkers(text string, cfg config, e *explainer) string {
+	if len(cfg.rules.Markers) == 0 {
+		return text
+	}
+	tok := tokenizer.NewTokenizer()
+	return e.record("definitions", text, tok.Reconstruct(tok.ExpandMarkers(tok.Tokenize(text), cfg.rules.Markers)))
+}
+
+// applyRules applies the enabled rules to text in order. By default articles
+// come after the rules that can change the word after an article.
+func applyRules(text string, cfg config, e *explainer) (string, []rules.Diagnostic) {
+	var diagnostics []rules.Diagnostic
+	for _, name := range cfg.enabledRules() {
+		switch name {
+		case "case":
+			result, found := rules.ApplyCaseWithOptions(text, cfg.rules)
+			text = e.record(name, text, result)
+			diagnostics = append(diagnostics, found...)
+		case "numbers":
+			text = e.record(name, text, rules.ApplyNumbers(text))
+		case "quotes":
+			text = e.record(name, text, rules.CleanQuotesWithOptions(text, cfg.rules))
+		case "punctuation":
+			text = e.record(name, text, rules.FixPunctuationWithOptions(text, cfg.rules))
+		case "articles":
+			text = e.record(name, text, rules.FixArticlesWithOptions(text, cfg.rules))
+		case "whitespace":
+			text = e.record(name, text, rules.NormalizeWhitespace(text, cfg.rules.Whitespace))
+		}
+	}
+	return text, diagnostics
+}
@@ -1,6 +1,50 @@
 package processor
 
-// Processor defines the interface for text processing
+import (
+	"fmt"
+	"go-reloaded/internal/rules"
+	"strings"
+)
+
+// Processor defines the interface for text processing.
+//
+// Process must be idempotent: running it again on its own output returns
+// that output unchanged, so already processed files are safe to reprocess.
+// Processors keep no state between calls and are safe for concurrent use.
 type Processor interface {
 	Process(text string) string
-}
\ No newline at end of file
+}
+
+// DiagnosticProcessor is a Processor that also reports problems it found,
+// such as marker counts clamped to their scope
+type DiagnosticProcessor interface {
+	Processor
+	ProcessWithDiagnostics(text string) (string, []rules.Diagnostic)
+}
+
+// ModeNames lists the processing modes in the order they are documented
+var ModeNames = []string{"pipeline", "fsm", "hybrid"}
+
+// factories maps each mode to the constructor of its processor
+var factories = map[string]func(...Option) ExplainProcessor{
+	"pipeline": func(opts ...Option) ExplainProcessor { return NewPipeline(opts...) },
+	"fsm":      func(opts ...Option) ExplainProcessor { return NewFSM(opts...) },
+	"hybrid":   func(opts ...Option) ExplainProcessor { return NewHybrid(opts...) },
+}
+
+// New creates the processor for the named mode, checking the rules given
+// with WithRules and the markers given with WithMarkers
+func New(mode string, opts ...Option) (ExplainProcessor, error) {
+	factory, ok := factories[mode]
+	if !ok {
+		return nil, fmt.Errorf("invalid mode %q. Use one of [%s]", mode, strings.Join(ModeNames, "|"))
+	}
+	cfg := newConfig(opts)
+	if err := ValidateRules(cfg.ruleNames); err != nil {
+		return nil, err
+	}
+	if err := cfg.rules.Markers.Validate(); err != nil {
+		return nil, err
+	}
+	return factory(opts...), nil
+}
@@ -1,134 +1,339 @@
 package processor
 
 import (
+	"fmt"
 	"go-reloaded/internal/rules"
+	"sort"
 	"strings"
-	"strconv"
+	"sync"
 )
 
-// RealtimeFSM processes characters as they're typed
+// RealtimeFSM processes characters as they're typed, giving the same final
+// output as Pipeline.Process on the whole input.
+//
+// Output becomes final at a blank line once nothing after it can change the
+// text before it. Numbered markers like (up, 5) can reach back to the start
+// of the text unless a scope limits them to their line, sentence or
+// paragraph, so with the default unbounded scope output only becomes final
+// on Flush. Preview shows the processed text so far at any time.
+//
+// The state machine tracks the word, quote or marker still being typed. It
+// keeps the input so edits anywhere in it can be applied, recomputing only
+// from the last final output before the edit.
+//
+// A RealtimeFSM is one editing session. Its methods are safe to call from
+// several goroutines, so one can render Preview while another types, but
+// concurrent edits apply in no particular order; give each text its own
+// RealtimeFSM.
 type RealtimeFSM struct {
-	state       FSMState
-	buffer      strings.Builder
-	markerBuf   strings.Builder
-	output      strings.Builder
-	lastWord    string
+	mu       sync.Mutex
+	cfg      config
+	pipeline *Pipeline
+
+	input    []rune
+	cursor   int
+	segments []segment
+
+	// committed is the end of the final output in input
+	committed int
+	realtimeState
+}
+
+// segment is a piece of final output and the input position after it.
+// Flushed segments end a text; others are final because of the character
+// at end.
+type segment struct {
+	end     int
+	text    string
+	flushed bool
+}
+
+// realtimeState is the state of the input after the final output
+type realtimeState struct {
+	state FSMState
+	// tailStart is the start of the word, quote or marker being typed
+	tailStart int
+	// spaceStart is the start of the whitespace run at the end of the
+	// input, or -1, and spaceNewlines the line breaks in it
+	spaceStart    int
+	spaceNewlines int
 }
 
 // NewRealtimeFSM creates a new real-time FSM
-func NewRealtimeFSM() *RealtimeFSM {
-	return &RealtimeFSM{state: Normal}
+func NewRealtimeFSM(opts ...Option) *RealtimeFSM {
+	cfg := newConfig(opts)
+	return &RealtimeFSM{
+		cfg:           cfg,
+		pipeline:      &Pipeline{cfg: cfg},
+		realtimeState: realtimeState{state: Normal, spaceStart: -1},
+	}
 }
 
-// ProcessChar handles a single character input and returns any output
+// ProcessChar appends a character to the input, moves the cursor to the end
+// and returns any output that became final
 func (r *RealtimeFSM) ProcessChar(char rune) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.input = append(r.input, char)
+	r.cursor = len(r.input)
+	return r.step(len(r.input) - 1)
+}
+
+// Flush makes all input final, as at the end of a text, and returns the
+// output that became final. Characters added afterwards start a new text.
+func (r *RealtimeFSM) Flush() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.committed == len(r.input) {
+		return ""
+	}
+	result := r.pipeline.Process(string(r.input[r.committed:]))
+	r.commit(segment{end: len(r.input), text: result, flushed: true})
+	return result
+}
+
+// step runs the character at position i of the input through the state
+// machine, first committing the paragraph before it if that is now final
+func (r *RealtimeFSM) step(i int) string {
+	char := r.input[i]
+	space := char == ' ' || char == '\t' || char == '\n' || char == '\r'
+
+	var result string
+	if !space && r.state == Normal && r.spaceNewlines >= 2 {
+		result = r.commitParagraph(i)
+	}
+
 	switch r.state {
 	case Normal:
-		return r.handleNormal(char)
+		switch {
+		case char == '(':
+			r.state = InMarker
+			r.tailStart = i
+		case char == '\'' && r.tailStart == i:
+			r.state = InQuotes
+		case space:
+			r.tailStart = i + 1
+		}
 	case InMarker:
-		return r.handleMarker(char)
+		if char == ')' {
+			r.state = Normal
+			r.tailStart = i + 1
+		}
 	case InQuotes:
-		return r.handleQuotes(char)
+		if char == '\'' {
+			r.state = Normal
+			r.tailStart = i + 1
+		}
+	}
+
+	switch {
+	case !space:
+		r.spaceStart, r.spaceNewlines = -1, 0
+	case r.spaceStart < 0:
+		r.spaceStart, r.spaceNewlines = i, 0
+	}
+	if char == '\n' {
+		r.spaceNewlines++
 	}
-	return ""
+	return result
 }
 
-func (r *RealtimeFSM) handleNormal(char rune) string {
-	switch char {
-	case '(':
-		r.lastWord = r.buffer.String()
-		r.buffer.Reset()
-		r.state = InMarker
-		r.markerBuf.Reset()
+// commitParagraph makes the input before the blank lines ending at position
+// next final, if no rule can act across them. It returns the final output.
+func (r *RealtimeFSM) commitParagraph(next int) string {
+	if r.cfg.rules.Scope == rules.ScopeUnbounded {
 		return ""
-		
-	case ' ', '\t', '\n':
-		word := r.buffer.String()
-		r.buffer.Reset()
-		if word != "" {
-			r.output.WriteString(word)
-		}
-		r.output.WriteRune(char)
-		result := r.output.String()
-		r.output.Reset()
-		// Apply article corrections to the result
-		result = rules.FixArticles(result)
-		return result
-		
-	case '\'':
-		word := r.buffer.String()
-		r.buffer.Reset()
-		if word != "" {
-			r.output.WriteString(word)
-		}
-		r.output.WriteRune(char)
-		r.state = InQuotes
-		return ""
-		
-	default:
-		r.buffer.WriteRune(char)
+	}
+	before := string(r.input[r.committed:r.spaceStart])
+	if !safeToSplit(before, string(r.input[next])) {
 		return ""
 	}
+	separator := string(r.input[r.spaceStart:next])
+	result := r.pipeline.Process(before) + r.cfg.normalizeWhitespace(separator)
+	r.commit(segment{end: next, text: result})
+	return result
 }
 
-func (r *RealtimeFSM) handleMarker(char rune) string {
-	if char == ')' {
-		marker := r.markerBuf.String()
-		r.state = Normal
-		
-		// Apply transformation
-		transformed := r.applyTransformation(r.lastWord, marker)
-		r.output.WriteString(transformed)
-		result := r.output.String()
-		r.output.Reset()
-		return result
+// commit records seg as final output
+func (r *RealtimeFSM) commit(seg segment) {
+	r.segments = append(r.segments, seg)
+	r.committed = seg.end
+	if r.tailStart < seg.end {
+		r.tailStart = seg.end
 	}
-	
-	r.markerBuf.WriteRune(char)
-	return ""
 }
 
-func (r *RealtimeFSM) handleQuotes(char rune) string {
-	r.output.WriteRune(char)
-	if char == '\'' {
-		r.state = Normal
-		result := r.output.String()
-		r.output.Reset()
-		return result
-	}
-	return ""
+// ApplyEdit replaces delete characters of the input at offset with insert.
+// Offsets count runes. Final output before the edit is kept, and the input
+// after it is processed again, stopping early once the final output after
+// the edit is known to be unchanged. An edit before text made final by
+// Flush joins it to the text after it.
+func (r *RealtimeFSM) ApplyEdit(offset, delete int, insert string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.applyEdit(offset, delete, insert)
 }
 
-func (r *RealtimeFSM) applyTransformation(word, marker string) string {
-	switch marker {
-	case "hex":
-		if val, err := strconv.ParseInt(word, 16, 64); err == nil {
-			return strconv.FormatInt(val, 10)
+func (r *RealtimeFSM) applyEdit(offset, delete int, insert string) error {
+	if offset < 0 || delete < 0 || offset+delete > len(r.input) {
+		return fmt.Errorf("edit at %d deleting %d is outside the input of length %d", offset, delete, len(r.input))
+	}
+	inserted := []rune(insert)
+	delta := len(inserted) - delete
+
+	// Keep the final output before the edit. A paragraph is only final
+	// because of the character after it, so that must be unchanged too.
+	keep := sort.Search(len(r.segments), func(i int) bool {
+		return r.segments[i].end > offset || r.segments[i].end == offset && !r.segments[i].flushed
+	})
+	start := 0
+	if keep > 0 {
+		start = r.segments[keep-1].end
+	}
+	old := append([]segment(nil), r.segments[keep:]...)
+	saved := r.realtimeState
+
+	input := make([]rune, 0, len(r.input)+delta)
+	input = append(input, r.input[:offset]...)
+	input = append(input, inserted...)
+	input = append(input, r.input[offset+delete:]...)
+	r.input = input
+	r.segments = r.segments[:keep]
+	r.committed = start
+	r.realtimeState = realtimeState{state: Normal, tailStart: start, spaceStart: -1}
+
+	editEnd := offset + len(inserted)
+	j := 0
+	for i := start; i < len(input); i++ {
+		if r.step(i) == "" || r.committed < editEnd {
+			continue
+		}
+
+		// If the old input was made final at the same place, with the
+		// same input after it, the rest is unchanged
+		for j < len(old) && old[j].end < r.committed-delta {
+			j++
 		}
-	case "bin":
-		if val, err := strconv.ParseInt(word, 2, 64); err == nil {
-			return strconv.FormatInt(val, 10)
+		if j < len(old) && old[j].end == r.committed-delta {
+			for _, seg := range old[j+1:] {
+				seg.end += delta
+				r.segments = append(r.segments, seg)
+			}
+			r.committed = r.segments[len(r.segments)-1].end
+			r.realtimeState = saved
+			r.tailStart += delta
+			if r.spaceStart >= 0 {
+				r.spaceStart += delta
+			}
+			break
 		}
-	case "up":
-		return strings.ToUpper(word)
-	case "low":
-		return strings.ToLower(word)
-	case "cap":
-		return strings.Title(strings.ToLower(word))
 	}
-	return word
+
+	switch {
+	case r.cursor >= offset+delete:
+		r.cursor += delta
+	case r.cursor > offset:
+		r.cursor = editEnd
+	}
+	return nil
+}
+
+// InsertChar inserts a character at the cursor and moves the cursor past it
+func (r *RealtimeFSM) InsertChar(char rune) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applyEdit(r.cursor, 0, string(char))
+}
+
+// DeleteChar deletes the character before the cursor, like backspace. It
+// reports false if the cursor is at the start of the input.
+func (r *RealtimeFSM) DeleteChar() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cursor == 0 {
+		return false
+	}
+	r.applyEdit(r.cursor-1, 1, "")
+	return true
+}
+
+// MoveCursor moves the cursor by delta characters, staying within the input
+func (r *RealtimeFSM) MoveCursor(delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setCursor(r.cursor + delta)
+}
+
+// SetCursor moves the cursor to pos, staying within the input
+func (r *RealtimeFSM) SetCursor(pos int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setCursor(pos)
+}
+
+func (r *RealtimeFSM) setCursor(pos int) {
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > len(r.input) {
+		pos = len(r.input)
+	}
+	r.cursor = pos
+}
+
+// Cursor returns the cursor position in characters
+func (r *RealtimeFSM) Cursor() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cursor
+}
+
+// Text returns the input
+func (r *RealtimeFSM) Text() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return string(r.input)
 }
 
-// GetCurrentBuffer returns current incomplete input
+// Output returns the output that is final so far
+func (r *RealtimeFSM) Output() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.output()
+}
+
+func (r *RealtimeFSM) output() string {
+	var result strings.Builder
+	for _, seg := range r.segments {
+		result.WriteString(seg.text)
+	}
+	return result.String()
+}
+
+// Preview returns the final output followed by the processed input before
+// the word, quote or marker still being typed, which GetCurrentBuffer
+// returns. Unlike Output, the end of it can still change.
+func (r *RealtimeFSM) Preview() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.output() + r.pipeline.Process(string(r.input[r.committed:r.tailStart]))
+}
+
+// GetCurrentBuffer returns current incomplete input: the word, quote or
+// marker still being typed
 func (r *RealtimeFSM) GetCurrentBuffer() string {
-	return r.buffer.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return string(r.input[r.tailStart:])
 }
 
 // Reset clears all state
 func (r *RealtimeFSM) Reset() {
-	r.state = Normal
-	r.buffer.Reset()
-	r.markerBuf.Reset()
-	r.output.Reset()
-	r.lastWord = ""
-}
\ No newline at end of file
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.input = nil
+	r.cursor = 0
+	r.segments = nil
+	r.committed = 0
+	r.realtimeState = realtimeState{state: Normal, spaceStart: -1}
+}
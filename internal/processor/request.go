@@ -0,0 +1,113 @@
+package processor
+
+import "go-reloaded/internal/rules"
+
+// Request asks to process text, as the HTTP API, the daemon and the
+// WebAssembly build decode it from JSON. An empty mode is "pipeline".
+type Request struct {
+	Text string `json:"text"`
+	Mode string `json:"mode"`
+	RequestOptions
+}
+
+// RequestOptions are the settings a request gives by name, with the names
+// of the command-line flags. Empty settings take their defaults.
+type RequestOptions struct {
+	Scope          string `json:"scope"`
+	Whitespace     string `json:"whitespace"`
+	Locale         string `json:"locale"`
+	UnknownMarkers string `json:"unknown-markers"`
+	// Rules lists the enabled rules in order, or is nil for all of them
+	Rules []string `json:"rules"`
+	// Markers maps the names of user-defined markers to their definitions
+	Markers map[string]string `json:"markers"`
+}
+
+// Result is the output of a request with the problems found. Diagnostics
+// is never nil, so it encodes as an empty list.
+type Result struct {
+	Output      string             `json:"output"`
+	Diagnostics []rules.Diagnostic `json:"diagnostics"`
+}
+
+// MarkerInfo describes a built-in marker
+type MarkerInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	TakesCount  bool   `json:"takesCount"`
+}
+
+// Options returns the processor options for the settings
+func (o RequestOptions) Options() ([]Option, error) {
+	opts, err := ParseOptions(o.Scope, o.Whitespace, o.Locale)
+	if err != nil {
+		return nil, err
+	}
+	if o.UnknownMarkers != "" {
+		policy, err := rules.ParseMarkerPolicy(o.UnknownMarkers)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithMarkerPolicy(policy))
+	}
+	if o.Rules != nil {
+		opts = append(opts, WithRules(o.Rules))
+	}
+	if len(o.Markers) > 0 {
+		opts = append(opts, WithMarkers(o.Markers))
+	}
+	return opts, nil
+}
+
+// Processor creates the processor the request asks for
+func (r Request) Processor() (ExplainProcessor, error) {
+	opts, err := r.Options()
+	if err != nil {
+		return nil, err
+	}
+	mode := r.Mode
+	if mode == "" {
+		mode = "pipeline"
+	}
+	return New(mode, opts...)
+}
+
+// Process processes the text of the request
+func (r Request) Process() (Result, error) {
+	proc, err := r.Processor()
+	if err != nil {
+		return Result{}, err
+	}
+	output, diagnostics := proc.ProcessWithDiagnostics(r.Text)
+	if diagnostics == nil {
+		diagnostics = []rules.Diagnostic{}
+	}
+	return Result{Output: output, Diagnostics: diagnostics}, nil
+}
+
+// Explain processes the text of the request, recording the steps. Steps
+// and Diagnostics are never nil, so they encode as empty lists.
+func (r Request) Explain() (Explanation, error) {
+	proc, err := r.Processor()
+	if err != nil {
+		return Explanation{}, err
+	}
+	explanation := proc.Explain(r.Text)
+	if explanation.Steps == nil {
+		explanation.Steps = []Step{}
+	}
+	if explanation.Diagnostics == nil {
+		explanation.Diagnostics = []rules.Diagnostic{}
+	}
+	return explanation, nil
+}
+
+// ListMarkers describes the built-in markers in the order of
+// rules.MarkerNames
+func ListMarkers() []MarkerInfo {
+	markers := make([]MarkerInfo, len(rules.MarkerNames))
+	for i, name := range rules.MarkerNames {
+		markers[i] = MarkerInfo{Name: name, Description: rules.MarkerDescriptions[name], TakesCount: rules.TakesCount(name)}
+	}
+	return markers
+}
@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"go-reloaded/internal/rules"
+	"strings"
+)
+
+// RuleNames lists the rules a processor applies, in their default order
+var RuleNames = []string{"case", "numbers", "quotes", "punctuation", "articles", "whitespace"}
+
+// ruleOrder lists pairs of rules that only work one way round: the first must
+// come before the second when both are enabled. Case markers can sit between
+// an article and its word, as in "a (cap) apple", so articles are only fixed
+// once case markers are gone.
+var ruleOrder = [][2]string{
+	{"case", "articles"},
+}
+
+// ParseRules parses a comma-separated list of rule names, as given on a
+// command line, and validates it
+func ParseRules(list string) ([]string, error) {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if err := ValidateRules(names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// ValidateRules checks that names only lists rules from RuleNames, each once,
+// in an order that works. Each problem found is joined into the error.
+func ValidateRules(names []string) error {
+	var problems []error
+	position := make(map[string]int)
+	for i, name := range names {
+		if _, seen := position[name]; seen {
+			problems = append(problems, fmt.Errorf("rule %q is listed twice", name))
+			continue
+		}
+		if !isRule(name) {
+			problems = append(problems, unknownRule(name))
+			continue
+		}
+		position[name] = i
+	}
+	for _, pair := range ruleOrder {
+		before, ok1 := position[pair[0]]
+		after, ok2 := position[pair[1]]
+		if ok1 && ok2 && after < before {
+			problems = append(problems, fmt.Errorf("rule %q must come after %q", pair[1], pair[0]))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// SkipRules returns names without the rules in skip, which must all be in
+// RuleNames. Spaces around the names in skip are ignored.
+func SkipRules(names, skip []string) ([]string, error) {
+	for i, name := range skip {
+		skip[i] = strings.TrimSpace(name)
+		if !isRule(skip[i]) {
+			return nil, unknownRule(skip[i])
+		}
+	}
+	kept := []string{}
+	for _, name := range names {
+		if !contains(skip, name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept, nil
+}
+
+func isRule(name string) bool {
+	return contains(RuleNames, name)
+}
+
+func unknownRule(name string) error {
+	return fmt.Errorf("unknown rule %q. Use some of [%s]", name, strings.Join(RuleNames, "|"))
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledRules returns the rules to apply, in order
+func (cfg config) enabledRules() []string {
+	if cfg.ruleNames == nil {
+		return RuleNames
+	}
+	return cfg.ruleNames
+}
+
+// enabled reports whether the named rule is applied
+func (cfg config) enabled(name string) bool {
+	return contains(cfg.enabledRules(), name)
+}
+
+// normalizeWhitespace applies the whitespace policy to text if the
+// whitespace rule is enabled
+func (cfg config) normalizeWhitespace(text string) string {
+	if !cfg.enabled("whitespace") {
+		return text
+	}
+	return rules.NormalizeWhitespace(text, cfg.rules.Whitespace)
+}
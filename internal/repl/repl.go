@@ -0,0 +1,280 @@
+// Package repl implements an interactive editor that shows the processed text
+// live as each key is pressed. It expects a terminal in raw mode, see MakeRaw.
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	inputPrompt   = "Input:   "
+	previewPrompt = "Preview: "
+	outputPrompt  = "Output:  "
+
+	// highlightStart and highlightEnd mark pending input in the preview
+	highlightStart = "\x1b[33m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// Keys that are not plain text
+const (
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyBackspace = 0x7f
+	keyCtrlH     = 0x08
+	keyNewline   = '\n'
+	keyEnter     = '\r'
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1b
+)
+
+// Session is an interactive editing session. Enter processes the input,
+// Ctrl-J or Alt-Enter starts a new line, Left and Right move the cursor, Up
+// and Down walk the history and Ctrl-D on an empty input, Ctrl-C or typing
+// quit ends the session.
+type Session struct {
+	in    *bufio.Reader
+	out   io.Writer
+	width int
+
+	fsm *processor.RealtimeFSM
+
+	history []string
+	histPos int
+	draft   string
+
+	// cursorRow is the row of the cursor relative to the first input row
+	cursorRow int
+}
+
+// New creates a session reading keys from in and drawing to out
+func New(in io.Reader, out io.Writer) *Session {
+	return &Session{
+		in:    bufio.NewReader(in),
+		out:   out,
+		width: 80,
+		fsm:   processor.NewRealtimeFSM(),
+	}
+}
+
+// SetWidth sets the terminal width used to lay out wrapped lines
+func (s *Session) SetWidth(width int) {
+	if width > 0 {
+		s.width = width
+	}
+}
+
+// History returns the inputs processed so far, oldest first
+func (s *Session) History() []string {
+	return s.history
+}
+
+// Run reads keys until the session ends or in is exhausted
+func (s *Session) Run() error {
+	s.render()
+	for {
+		r, _, err := s.in.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch r {
+		case keyCtrlC:
+			s.write("\r\n")
+			return nil
+		case keyCtrlD:
+			if s.fsm.Text() == "" {
+				s.write("\r\n")
+				return nil
+			}
+			if s.submit() {
+				return nil
+			}
+		case keyEnter:
+			if s.submit() {
+				return nil
+			}
+		case keyNewline:
+			s.insert('\n')
+		case keyBackspace, keyCtrlH:
+			s.fsm.DeleteChar()
+		case keyCtrlU:
+			s.setInput("")
+		case keyEscape:
+			s.handleEscape()
+		default:
+			if r >= ' ' || r == '\t' {
+				s.insert(r)
+			}
+		}
+		s.render()
+	}
+}
+
+// handleEscape reads the rest of an escape sequence. Arrows, Home and End
+// move the cursor or walk the history, Alt-Enter starts a new line and other
+// sequences are ignored.
+func (s *Session) handleEscape() {
+	r, _, err := s.in.ReadRune()
+	if err != nil {
+		return
+	}
+	switch r {
+	case keyEnter:
+		s.insert('\n')
+		return
+	case '[', 'O':
+	default:
+		return
+	}
+
+	// Skip parameters up to the final byte of the sequence
+	for {
+		r, _, err = s.in.ReadRune()
+		if err != nil || r >= 0x40 && r <= 0x7e {
+			break
+		}
+	}
+	switch r {
+	case 'A':
+		s.historyPrev()
+	case 'B':
+		s.historyNext()
+	case 'C':
+		s.fsm.MoveCursor(1)
+	case 'D':
+		s.fsm.MoveCursor(-1)
+	case 'H':
+		s.fsm.SetCursor(0)
+	case 'F':
+		s.fsm.SetCursor(len([]rune(s.fsm.Text())))
+	}
+}
+
+// insert adds r to the input at the cursor
+func (s *Session) insert(r rune) {
+	s.fsm.InsertChar(r)
+}
+
+// setInput replaces the input and moves the cursor to its end
+func (s *Session) setInput(text string) {
+	s.fsm.Reset()
+	for _, r := range text {
+		s.fsm.ProcessChar(r)
+	}
+}
+
+func (s *Session) historyPrev() {
+	if s.histPos == 0 {
+		return
+	}
+	if s.histPos == len(s.history) {
+		s.draft = s.fsm.Text()
+	}
+	s.histPos--
+	s.setInput(s.history[s.histPos])
+}
+
+func (s *Session) historyNext() {
+	if s.histPos >= len(s.history) {
+		return
+	}
+	s.histPos++
+	if s.histPos == len(s.history) {
+		s.setInput(s.draft)
+		return
+	}
+	s.setInput(s.history[s.histPos])
+}
+
+// submit prints the processed input and starts a new one. It reports
+// whether the input asked to end the session.
+func (s *Session) submit() bool {
+	text := s.fsm.Text()
+	if strings.ToLower(strings.TrimSpace(text)) == "quit" {
+		s.clear()
+		s.write(inputPrompt + indent(text, inputPrompt) + "\r\n")
+		return true
+	}
+
+	output := s.fsm.Output() + s.fsm.Flush()
+	s.clear()
+	s.write(inputPrompt + indent(text, inputPrompt) + "\r\n")
+	s.write(outputPrompt + indent(output, outputPrompt) + "\r\n\r\n")
+
+	if text != "" {
+		s.history = append(s.history, text)
+	}
+	s.histPos = len(s.history)
+	s.draft = ""
+	s.setInput("")
+	return false
+}
+
+// render redraws the input and the live preview, leaving the terminal
+// cursor at the input cursor
+func (s *Session) render() {
+	text := s.fsm.Text()
+	done := s.fsm.Preview()
+	pending := s.fsm.GetCurrentBuffer()
+
+	s.clear()
+	inputRows, _ := s.layout(inputPrompt, text)
+	cursorRows, col := s.layout(inputPrompt, string([]rune(text)[:s.fsm.Cursor()]))
+	previewRows, _ := s.layout(previewPrompt, done+pending)
+
+	var b strings.Builder
+	b.WriteString(inputPrompt + indent(text, inputPrompt) + "\r\n")
+	b.WriteString(previewPrompt + indent(done, previewPrompt))
+	if pending != "" {
+		b.WriteString(highlightStart + indent(pending, previewPrompt) + highlightEnd)
+	}
+	fmt.Fprintf(&b, "\x1b[%dA\x1b[%dG", previewRows+inputRows-cursorRows, col)
+	s.write(b.String())
+	s.cursorRow = cursorRows - 1
+}
+
+// clear moves the cursor to the first input row and erases everything below
+func (s *Session) clear() {
+	if s.cursorRow > 0 {
+		s.write(fmt.Sprintf("\x1b[%dA", s.cursorRow))
+	}
+	s.write("\r\x1b[J")
+	s.cursorRow = 0
+}
+
+// layout returns the number of terminal rows text takes after prompt, with
+// continuation lines indented to match, and the column after its last rune
+func (s *Session) layout(prompt, text string) (rows, col int) {
+	for _, line := range strings.Split(text, "\n") {
+		length := utf8.RuneCountInString(prompt) + utf8.RuneCountInString(line)
+		lineRows := (length + s.width - 1) / s.width
+		if lineRows == 0 {
+			lineRows = 1
+		}
+		rows += lineRows
+		col = length - (lineRows-1)*s.width + 1
+	}
+	if col > s.width {
+		col = s.width
+	}
+	return rows, col
+}
+
+func (s *Session) write(text string) {
+	io.WriteString(s.out, text)
+}
+
+// indent turns the line breaks of text into terminal line breaks followed by
+// enough spaces to line up with prompt
+func indent(text, prompt string) string {
+	return strings.ReplaceAll(text, "\n", "\r\n"+strings.Repeat(" ", utf8.RuneCountInString(prompt)))
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// MakeRaw is only supported on Linux. Elsewhere it always fails, and callers
+// fall back to line-by-line input.
+func MakeRaw(fd int) (func() error, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+// Width returns 0, as the terminal width is unknown
+func Width(fd int) int {
+	return 0
+}
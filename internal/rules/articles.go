@@ -1,75 +1,161 @@
 package rules
 
 import (
-	"regexp"
 	"strings"
 )
 
-// FixArticles changes "a" to "an" before vowels and silent h
+// silentH lists words starting with a silent h, which take "an" instead of "a"
+var silentH = []string{"honest", "hour", "honor", "heir"}
+
+// ArticleExceptions lists words whose first sound does not match their first
+// letter. Entries match the start of a word, ignoring case, so "hour" also
+// covers "hourly".
+type ArticleExceptions struct {
+	// An lists words that take "an" though they start with a consonant,
+	// in addition to the built-in silent h words
+	An []string
+	// A lists words that take "a" though they start with a vowel, like
+	// "university"
+	A []string
+}
+
+// FixArticles changes "a" to "an" before vowels and silent h, and "an" back
+// to "a" before consonants. Each article only looks at the word after it, so
+// running FixArticles on its own output changes nothing.
 func FixArticles(text string) string {
-	// Handle silent h words first
-	silentH := []string{"honest", "hour", "honor", "heir"}
-	for _, word := range silentH {
-		// Case insensitive matching for silent h words
-		text = regexp.MustCompile(`\ba\s+`+word).ReplaceAllString(text, "an "+word)
-		text = regexp.MustCompile(`\bA\s+`+word).ReplaceAllString(text, "An "+word)
-		text = regexp.MustCompile(`\ba\s+`+strings.Title(word)).ReplaceAllString(text, "an "+strings.Title(word))
-		text = regexp.MustCompile(`\bA\s+`+strings.Title(word)).ReplaceAllString(text, "An "+strings.Title(word))
+	return FixArticlesWithOptions(text, Options{})
+}
+
+// FixArticlesWithOptions is FixArticles with the exceptions in opts.Articles
+func FixArticlesWithOptions(text string, opts Options) string {
+	var result strings.Builder
+	result.Grow(len(text))
+	last := 0
+	forEachWord(text, func(start, end int) {
+		result.WriteString(text[last:start])
+		result.WriteString(fixArticle(text[start:end], nextWord(text[end:]), opts.Articles))
+		last = end
+	})
+	result.WriteString(text[last:])
+	return result.String()
+}
+
+// CheckArticles reports the articles FixArticlesWithOptions would change,
+// without changing them
+func CheckArticles(text string, opts Options) []Diagnostic {
+	var diagnostics []Diagnostic
+	var lines *LineIndex
+	forEachWord(text, func(start, end int) {
+		article, next := text[start:end], nextWord(text[end:])
+		if fixed := fixArticle(article, next, opts.Articles); fixed != article {
+			if lines == nil {
+				lines = NewLineIndex(text)
+			}
+			diagnostics = append(diagnostics, lines.Diagnostic(start, Warning, "%q should be %q before %q", article, fixed, next))
+		}
+	})
+	return diagnostics
+}
+
+// forEachWord calls fn with the start and end of each word in text; \b
+// semantics match the old regex rules
+func forEachWord(text string, fn func(start, end int)) {
+	i := 0
+	for i < len(text) {
+		if !isWordByte(text[i]) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(text) && isWordByte(text[end]) {
+			end++
+		}
+		fn(i, end)
+		i = end
+	}
+}
+
+// fixArticle returns the corrected form of article given the word after it
+func fixArticle(article, next string, exceptions ArticleExceptions) string {
+	if next == "" || !isLetter(next[0]) {
+		return article
 	}
-	
-	// Handle vowel words with proper case preservation
-	vowelRegex := regexp.MustCompile(`\b(a)\s+([aeiouAEIOU]\w*)`)
-	text = vowelRegex.ReplaceAllString(text, "an $2")
-	
-	// For uppercase A, preserve the uppercase in "An"
-	vowelRegexUpper := regexp.MustCompile(`\b(A)\s+([aeiouAEIOU]\w*)`)
-	text = vowelRegexUpper.ReplaceAllStringFunc(text, func(match string) string {
-		parts := strings.Fields(match)
-		if len(parts) == 2 {
-			word := parts[1]
+
+	switch article {
+	case "a":
+		if takesAn(next, exceptions) {
+			return "an"
+		}
+	case "A":
+		if takesAn(next, exceptions) {
 			// If next word is all uppercase, use "AN"
-			if word == strings.ToUpper(word) && len(word) > 1 {
-				return "AN " + word
+			if next == strings.ToUpper(next) && len(next) > 1 {
+				return "AN"
 			}
 			// Otherwise use "An" to preserve the capital
-			return "An " + word
+			return "An"
 		}
-		return match
-	})
-	
-	// Fix incorrect "an" before consonants (except silent h)
-	consonantRegex := regexp.MustCompile(`\ban\s+([bcdfgjklmnpqrstvwxyzBCDFGJKLMNPQRSTVWXYZ]\w*)`)
-	text = consonantRegex.ReplaceAllStringFunc(text, func(match string) string {
-		parts := strings.Fields(match)
-		if len(parts) == 2 {
-			word := parts[1]
-			// Check if it's a silent h word
-			for _, silentWord := range silentH {
-				if strings.EqualFold(word, silentWord) {
-					return match // Keep "an" for silent h
-				}
-			}
-			return "a " + word
+	case "an":
+		if !takesAn(next, exceptions) {
+			return "a"
 		}
-		return match
-	})
-	
-	consonantRegexUpper := regexp.MustCompile(`\b(AN)\s+([bcdfgjklmnpqrstvwxyzBCDFGJKLMNPQRSTVWXYZ]\w*)`)
-	text = consonantRegexUpper.ReplaceAllStringFunc(text, func(match string) string {
-		parts := strings.Fields(match)
-		if len(parts) == 2 {
-			word := parts[1]
-			// Check if it's a silent h word
-			for _, silentWord := range silentH {
-				if strings.EqualFold(word, silentWord) {
-					return match // Keep "AN" for silent h
-				}
-			}
+	case "AN":
+		if !takesAn(next, exceptions) {
 			// Preserve uppercase: AN -> A
-			return "A " + word
+			return "A"
 		}
-		return match
-	})
-	
-	return text
-}
\ No newline at end of file
+	}
+	return article
+}
+
+// nextWord returns the word following an article, or "" if the article is
+// not followed by whitespace
+func nextWord(rest string) string {
+	i := 0
+	for i < len(rest) && isSpaceByte(rest[i]) {
+		i++
+	}
+	if i == 0 {
+		return ""
+	}
+	end := i
+	for end < len(rest) && isWordByte(rest[end]) {
+		end++
+	}
+	return rest[i:end]
+}
+
+// takesAn reports whether word starts with a vowel sound
+func takesAn(word string, exceptions ArticleExceptions) bool {
+	lower := strings.ToLower(word)
+	if hasAnyPrefix(lower, exceptions.A) {
+		return false
+	}
+	if strings.IndexByte("aeiouAEIOU", word[0]) >= 0 {
+		return true
+	}
+	return hasAnyPrefix(lower, silentH) || hasAnyPrefix(lower, exceptions.An)
+}
+
+// hasAnyPrefix reports whether lower starts with any of words, ignoring
+// their case
+func hasAnyPrefix(lower string, words []string) bool {
+	for _, word := range words {
+		if word != "" && strings.HasPrefix(lower, strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
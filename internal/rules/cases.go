@@ -3,253 +3,147 @@ package rules
 import (
 	"regexp"
 	"strconv"
-	"strings"
 )
 
-// ApplyCase processes up, low, cap transformations
+// caseMarkerRegex matches (up), (low), (cap), their numbered forms, forward
+// forms like (up>, 2) and range forms like (up:begin). Its literal "("
+// prefix lets the regexp engine skip ahead between markers.
+var caseMarkerRegex = regexp.MustCompile(`\((up|low|cap)(>|:begin|:end)?(?:,\s*(\d+))?\)`)
+
+// ApplyCase processes up, low, cap transformations in a single left-to-right
+// pass. Each marker transforms up to n words before it, or one word when no
+// count is given, so chained markers like (cap) (up) apply in order. Forward
+// markers like (up>, 2) transform the words after them, and (up:begin) ...
+// (up:end) the words between them, across punctuation and quotes.
 func ApplyCase(text string) string {
-	// Handle multiple consecutive commands first
-	text = handleMultipleCommands(text)
-	// Handle sequential numbered commands
-	text = handleSequentialCommands(text)
-	
-	// Handle (up, n) - uppercase n words before the command
-	upNRegex := regexp.MustCompile(`((?:\S+\s+)*\S*)\s+\(up,\s*(\d+)\)`)
-	text = upNRegex.ReplaceAllStringFunc(text, func(match string) string {
-		re := regexp.MustCompile(`^(.*)\s+\(up,\s*(\d+)\)$`)
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 3 {
-			allText := strings.TrimSpace(matches[1])
-			nStr := strings.TrimSpace(matches[2])
-			if n, err := strconv.Atoi(nStr); err == nil && n > 0 {
-				words := strings.Fields(allText)
-				if len(words) > 0 {
-					// Transform min(n, available_words) words from the end
-					startIdx := len(words) - n
-					if startIdx < 0 {
-						startIdx = 0
-					}
-					for i := startIdx; i < len(words); i++ {
-						words[i] = strings.ToUpper(words[i])
-					}
-					return strings.Join(words, " ")
-				}
-			}
-		}
-		return match
-	})
+	result, _ := ApplyCaseWithOptions(text, Options{})
+	return result
+}
+
+// ApplyCaseWithOptions is ApplyCase with numbered markers limited to
+// opts.Scope. Counts reaching past the scope are clamped and reported as
+// warnings. Ranges are not limited.
+func ApplyCaseWithOptions(text string, opts Options) (string, []Diagnostic) {
+	matches := caseMarkerRegex.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text, nil
+	}
 
-	// Handle (low, n) - lowercase n words before the command
-	lowNRegex := regexp.MustCompile(`((?:\S+\s+)*\S*)\s+\(low,\s*(\d+)\)`)
-	text = lowNRegex.ReplaceAllStringFunc(text, func(match string) string {
-		re := regexp.MustCompile(`^(.*)\s+\(low,\s*(\d+)\)$`)
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 3 {
-			allText := strings.TrimSpace(matches[1])
-			nStr := strings.TrimSpace(matches[2])
-			if n, err := strconv.Atoi(nStr); err == nil && n > 0 {
-				words := strings.Fields(allText)
-				if len(words) > 0 {
-					// Transform min(n, available_words) words from the end
-					startIdx := len(words) - n
-					if startIdx < 0 {
-						startIdx = 0
-					}
-					for i := startIdx; i < len(words); i++ {
-						words[i] = strings.ToLower(words[i])
-					}
-					return strings.Join(words, " ")
-				}
+	var buf WordBuffer
+	var diagnostics []Diagnostic
+	var lines *LineIndex
+	warn := func(offset int, message string) {
+		if lines == nil {
+			lines = NewLineIndex(text)
+		}
+		diagnostics = append(diagnostics, lines.Diagnostic(offset, Warning, "%s", message))
+	}
+	markers, partners := caseMarkers(text, matches)
+	waiting := CaseMarkers{Buffer: &buf, Scope: opts.Scope, Warn: warn}
+	last := 0
+	for i, m := range matches {
+		marker := markers[i]
+		if marker.Direction == Backward {
+			// The marker must be separated from the words around it, even
+			// if a forward marker before it took the space
+			if !ClosesBackward(text, m[0], m[1]) {
+				continue
 			}
+		} else if partners[i] < 0 && marker.Direction != Forward || marker.Count == 0 {
+			// Unpaired and misplaced markers are left as text
+			continue
 		}
-		return match
-	})
+		buf.Write(text[last:m[0]])
+		last = m[1]
+		waiting.Flush(false)
 
-	// Handle (cap, n) - capitalize n words before the command
-	capNRegex := regexp.MustCompile(`((?:\S+\s+)*\S*)\s+\(cap,\s*(\d+)\)`)
-	text = capNRegex.ReplaceAllStringFunc(text, func(match string) string {
-		re := regexp.MustCompile(`^(.*)\s+\(cap,\s*(\d+)\)$`)
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 3 {
-			allText := strings.TrimSpace(matches[1])
-			nStr := strings.TrimSpace(matches[2])
-			if n, err := strconv.Atoi(nStr); err == nil && n > 0 {
-				words := strings.Fields(allText)
-				if len(words) > 0 {
-					// Transform min(n, available_words) words from the end
-					startIdx := len(words) - n
-					if startIdx < 0 {
-						startIdx = 0
-					}
-					for i := startIdx; i < len(words); i++ {
-						// Don't override already uppercase words
-						if words[i] != strings.ToUpper(words[i]) || len(words[i]) == 1 {
-							words[i] = strings.Title(strings.ToLower(words[i]))
-						}
-					}
-					return strings.Join(words, " ")
-				}
+		switch marker.Direction {
+		case Forward:
+			if after := LeadingSpace(text[last:]); waiting.Forward(marker, m[0], after) {
+				last += len(after)
 			}
+			continue
+		case RangeBegin:
+			if after := LeadingSpace(text[last:]); waiting.Begin(marker, m[0], after) {
+				last += len(after)
+			}
+			continue
+		case RangeEnd:
+			if after := LeadingSpace(text[last:]); waiting.End(marker, after) {
+				last += len(after)
+			}
+			continue
 		}
-		return match
-	})
-
-
-
-	// Handle single word transformations
-	upRegex := regexp.MustCompile(`(\S+)\s+\(up\)`)
-	text = upRegex.ReplaceAllStringFunc(text, func(match string) string {
-		word := strings.Fields(match)[0]
-		return strings.ToUpper(word)
-	})
-
-	lowRegex := regexp.MustCompile(`(\S+)\s+\(low\)`)
-	text = lowRegex.ReplaceAllStringFunc(text, func(match string) string {
-		word := strings.Fields(match)[0]
-		return strings.ToLower(word)
-	})
 
-	capRegex := regexp.MustCompile(`(\S+)\s+\(cap\)`)
-	text = capRegex.ReplaceAllStringFunc(text, func(match string) string {
-		word := strings.Fields(match)[0]
-		// Don't override already uppercase words
-		if word == strings.ToUpper(word) && len(word) > 1 {
-			return word
+		n := marker.Count
+		if n <= 0 || buf.WordCount() == 0 {
+			// Leave markers that cannot apply untouched
+			buf.Write(text[m[0]:m[1]])
+			continue
 		}
-		return strings.Title(strings.ToLower(word))
-	})
 
-	return text
-}
-
-// handleMultipleCommands processes consecutive commands like (cap) (up)
-func handleMultipleCommands(text string) string {
-	// Handle patterns like "word (cmd1) (cmd2)" where both are case commands
-	multiCmdRegex := regexp.MustCompile(`(\S+)\s+\((up|low|cap)\)\s+\((up|low|cap)\)`)
-	text = multiCmdRegex.ReplaceAllStringFunc(text, func(match string) string {
-		re := regexp.MustCompile(`^(\S+)\s+\((up|low|cap)\)\s+\((up|low|cap)\)$`)
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 4 {
-			word := matches[1]
-			firstCmd := matches[2]
-			secondCmd := matches[3]
-			
-			// Apply first command
-			switch firstCmd {
-			case "up":
-				word = strings.ToUpper(word)
-			case "low":
-				word = strings.ToLower(word)
-			case "cap":
-				word = strings.Title(strings.ToLower(word))
+		if marker.Numbered {
+			var warning string
+			if n, warning = buf.ClampToScope(text[m[0]:m[1]], n, opts.Scope); warning != "" {
+				warn(m[0], warning)
 			}
-			
-			// Apply second command
-			switch secondCmd {
-			case "up":
-				return strings.ToUpper(word)
-			case "low":
-				return strings.ToLower(word)
-			case "cap":
-				return strings.Title(strings.ToLower(word))
+			if n == 0 {
+				buf.Write(text[m[0]:m[1]])
+				continue
 			}
-			
-			return word
 		}
-		return match
-	})
-	
-	// Handle mixed commands like "word (case) (hex)" - apply case, keep other command
-	mixedCmdRegex := regexp.MustCompile(`(\S+)\s+\((up|low|cap)\)\s+\(([^)]+)\)`)
-	text = mixedCmdRegex.ReplaceAllStringFunc(text, func(match string) string {
-		re := regexp.MustCompile(`^(\S+)\s+\((up|low|cap)\)\s+\(([^)]+)\)$`)
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 4 {
-			word := matches[1]
-			caseCmd := matches[2]
-			otherCmd := matches[3]
-			
-			// Apply case command
-			switch caseCmd {
-			case "up":
-				word = strings.ToUpper(word)
-			case "low":
-				word = strings.ToLower(word)
-			case "cap":
-				word = strings.Title(strings.ToLower(word))
-			}
-			
-			// Return with the other command preserved
-			return word + " (" + otherCmd + ")"
+
+		if after := LeadingSpace(text[last:]); buf.DropSpace(after) {
+			last += len(after)
 		}
-		return match
-	})
-	
-	return text
+		buf.TransformLast(n, caseTransforms[marker.Name])
+		buf.EndWord()
+	}
+	buf.Write(text[last:])
+	waiting.Flush(true)
+
+	return buf.String(), diagnostics
 }
 
-// handleSequentialCommands processes sequential numbered commands like (cap,2) (low,3)
-func handleSequentialCommands(text string) string {
-	// Handle patterns like "words (cmd1,n1) (cmd2,n2)"
-	seqCmdRegex := regexp.MustCompile(`((?:\S+\s+)*\S*)\s+\((up|low|cap),\s*(\d+)\)\s+\((up|low|cap),\s*(\d+)\)`)
-	text = seqCmdRegex.ReplaceAllStringFunc(text, func(match string) string {
-		re := regexp.MustCompile(`^((?:\S+\s+)*\S*)\s+\((up|low|cap),\s*(\d+)\)\s+\((up|low|cap),\s*(\d+)\)$`)
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 6 {
-			allText := strings.TrimSpace(matches[1])
-			firstCmd := matches[2]
-			firstN, _ := strconv.Atoi(matches[3])
-			secondCmd := matches[4]
-			secondN, _ := strconv.Atoi(matches[5])
-			
-			words := strings.Fields(allText)
-			if len(words) == 0 {
-				return match
+// caseMarkers reads the markers matched by caseMarkerRegex and pairs up the
+// range markers. Markers that cannot apply where they are placed get a
+// count of zero.
+func caseMarkers(text string, matches [][]int) ([]Marker, []int) {
+	markers := make([]Marker, len(matches))
+	for i, m := range matches {
+		marker := Marker{Name: text[m[2]:m[3]], Count: 1}
+		if m[4] >= 0 {
+			_, marker.Direction, _ = splitDirection(text[m[2]:m[5]])
+		}
+		if m[6] >= 0 {
+			n, err := strconv.Atoi(text[m[6]:m[7]])
+			if err != nil {
+				n = 0
 			}
-			
-			// Apply first command
-			if firstN > 0 && firstN <= len(words) {
-				startIdx := len(words) - firstN
-				if startIdx < 0 {
-					startIdx = 0
-				}
-				for i := startIdx; i < len(words); i++ {
-					switch firstCmd {
-					case "up":
-						words[i] = strings.ToUpper(words[i])
-					case "low":
-						words[i] = strings.ToLower(words[i])
-					case "cap":
-						if words[i] != strings.ToUpper(words[i]) || len(words[i]) == 1 {
-							words[i] = strings.Title(strings.ToLower(words[i]))
-						}
-					}
-				}
+			marker.Count, marker.Numbered = n, true
+		}
+		switch marker.Direction {
+		case Forward, RangeBegin:
+			if !OpensForward(text, m[0], m[1]) {
+				marker.Count = 0
 			}
-			
-			// Apply second command (may overlap with first)
-			if secondN > 0 && secondN <= len(words) {
-				startIdx := len(words) - secondN
-				if startIdx < 0 {
-					startIdx = 0
-				}
-				for i := startIdx; i < len(words); i++ {
-					switch secondCmd {
-					case "up":
-						words[i] = strings.ToUpper(words[i])
-					case "low":
-						words[i] = strings.ToLower(words[i])
-					case "cap":
-						if words[i] != strings.ToUpper(words[i]) || len(words[i]) == 1 {
-							words[i] = strings.Title(strings.ToLower(words[i]))
-						}
-					}
-				}
+		case RangeEnd:
+			if !ClosesBackward(text, m[0], m[1]) {
+				marker.Count = 0
 			}
-			
-			return strings.Join(words, " ")
 		}
-		return match
-	})
-	return text
-}
\ No newline at end of file
+		if marker.Numbered && (marker.Direction == RangeBegin || marker.Direction == RangeEnd) {
+			marker.Count = 0
+		}
+		markers[i] = marker
+	}
+
+	// Only well-placed range markers take part in pairing
+	ranges := make([]Marker, len(markers))
+	for i, marker := range markers {
+		if marker.Count > 0 {
+			ranges[i] = marker
+		}
+	}
+	return markers, MatchRanges(ranges)
+}
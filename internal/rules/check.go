@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CheckPunctuation reports the spacing FixPunctuationWithOptions would
+// change, without changing it
+func CheckPunctuation(text string, opts Options) []Diagnostic {
+	return checkSpacing(text, FixPunctuationWithOptions(text, opts))
+}
+
+// CheckQuotes reports the spacing inside quotes CleanQuotesWithOptions would
+// change, and quote marks that are never closed
+func CheckQuotes(text string, opts Options) []Diagnostic {
+	diagnostics := checkSpacing(text, CleanQuotesWithOptions(text, opts))
+	return SortDiagnostics(append(diagnostics, checkQuoteBalance(text, opts.Locale)...))
+}
+
+// checkSpacing reports where the whitespace of fixed differs from text. The
+// rules it is used for only change whitespace, so the rest of the two texts
+// lines up.
+func checkSpacing(text, fixed string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var lines *LineIndex
+	i, j := 0, 0
+	for {
+		had, want := leadingWhitespace(text[i:]), leadingWhitespace(fixed[j:])
+		if had != want {
+			if lines == nil {
+				lines = NewLineIndex(text)
+			}
+			prev, _ := utf8.DecodeLastRuneInString(text[:i])
+			next, _ := utf8.DecodeRuneInString(text[i+len(had):])
+			diagnostics = append(diagnostics, lines.Diagnostic(i, Warning, "%s", spacingMessage(prev, next, had, want)))
+		}
+		i, j = i+len(had), j+len(want)
+		if i == len(text) || j == len(fixed) {
+			return diagnostics
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if fr, _ := utf8.DecodeRuneInString(fixed[j:]); fr != r {
+			// Not a spacing change, which the callers do not make
+			return diagnostics
+		}
+		i, j = i+size, j+size
+	}
+}
+
+// spacingMessage describes changing the whitespace had between prev and next
+// to want. The change is put on the side of the punctuation or quote mark.
+func spacingMessage(prev, next rune, had, want string) string {
+	side, mark := "before", next
+	if !IsPunctuation(next) && !IsQuote(next) || next == utf8.RuneError {
+		side, mark = "after", prev
+	}
+	switch {
+	case want == "":
+		return fmt.Sprintf("unexpected space %s %q", side, string(mark))
+	case had == "":
+		return fmt.Sprintf("missing %s %s %q", describeSpace(want), side, string(mark))
+	}
+	return fmt.Sprintf("use %s %s %q", describeSpace(want), side, string(mark))
+}
+
+func describeSpace(space string) string {
+	switch space {
+	case " ":
+		return "space"
+	case string(NarrowNoBreakSpace):
+		return "narrow no-break space"
+	}
+	return fmt.Sprintf("%q", space)
+}
+
+// leadingWhitespace returns the Unicode whitespace at the start of text
+func leadingWhitespace(text string) string {
+	end := 0
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end += size
+	}
+	return text[:end]
+}
+
+// checkQuoteBalance reports quote marks without a partner: single and
+// double quotes left over after pairing them in order, and the quote marks
+// of locale that are opened and not closed or closed and not opened. A
+// single quote between two letters is an apostrophe.
+func checkQuoteBalance(text string, locale Locale) []Diagnostic {
+	var unpaired []int
+	straight := map[rune]int{'\'': -1, '"': -1}
+	var open []int
+	for i, r := range text {
+		if first, ok := straight[r]; ok && !(r == '\'' && IsApostrophe(text, i)) {
+			if first < 0 {
+				straight[r] = i
+			} else {
+				straight[r] = -1
+			}
+		}
+		for _, pair := range localeProfiles[locale].quotes {
+			switch {
+			case r == pair.open:
+				open = append(open, i)
+			case r == pair.close && len(open) > 0 && openedWith(text, open[len(open)-1], pair.open):
+				open = open[:len(open)-1]
+			case r == pair.close:
+				unpaired = append(unpaired, i)
+			}
+		}
+	}
+	for _, i := range straight {
+		if i >= 0 {
+			unpaired = append(unpaired, i)
+		}
+	}
+	unpaired = append(unpaired, open...)
+	if len(unpaired) == 0 {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	lines := NewLineIndex(text)
+	for _, i := range unpaired {
+		r, _ := utf8.DecodeRuneInString(text[i:])
+		diagnostics = append(diagnostics, lines.Diagnostic(i, Warning, "quote %q has no partner", string(r)))
+	}
+	return SortDiagnostics(diagnostics)
+}
+
+// openedWith reports whether the quote mark at offset in text is open
+func openedWith(text string, offset int, open rune) bool {
+	r, _ := utf8.DecodeRuneInString(text[offset:])
+	return r == open
+}
@@ -0,0 +1,147 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MarkerDefinitions maps the names of user-defined markers to what they
+// stand for. A definition is either the name of another marker, which makes
+// an alias like upper: up, or text with markers in it, which makes a macro
+// like shout: "(up) !" or name: "(cap, 2)".
+//
+// A count given where a defined marker is used, as in (shout, 3), is passed
+// on to the markers in its definition that have no count of their own.
+type MarkerDefinitions map[string]string
+
+// markerReferenceRegex matches a marker in a definition: its name and, if it
+// has one, the count after the comma
+var markerReferenceRegex = regexp.MustCompile(`\(([A-Za-z]+)(?:,([^()]*))?\)`)
+
+// template returns the definition of name as text with markers in it
+func (d MarkerDefinitions) template(name string) string {
+	definition := strings.TrimSpace(d[name])
+	if isWord(definition) {
+		return "(" + definition + ")"
+	}
+	return definition
+}
+
+// references returns the names of the markers used in the definition of
+// name, in order
+func (d MarkerDefinitions) references(name string) []string {
+	var names []string
+	for _, m := range markerReferenceRegex.FindAllStringSubmatch(d.template(name), -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// Check reports the first problem with the definition of name: a name that
+// is not a word or replaces a built-in marker, an empty definition, a marker
+// that is neither built in nor defined, or a definition that leads back to
+// name
+func (d MarkerDefinitions) Check(name string) error {
+	switch {
+	case !isWord(name):
+		return fmt.Errorf("marker name %q must be a single word of letters", name)
+	case isBuiltinMarker(name):
+		return fmt.Errorf("marker (%s) would replace the built-in marker", name)
+	case strings.TrimSpace(d[name]) == "":
+		return fmt.Errorf("marker (%s) has an empty definition", name)
+	}
+	for _, ref := range d.references(name) {
+		if _, defined := d[ref]; !defined && !isBuiltinMarker(ref) {
+			return fmt.Errorf("marker (%s) uses unknown marker (%s). Use one of [%s] or a defined marker", name, ref, strings.Join(MarkerNames, "|"))
+		}
+	}
+	if cycle := d.cycle([]string{name}); cycle != nil {
+		return fmt.Errorf("marker (%s) is defined in terms of itself: (%s)", name, strings.Join(cycle, ") → ("))
+	}
+	return nil
+}
+
+// cycle follows the definitions from the last name in path and returns the
+// path that leads back to its first name, or nil
+func (d MarkerDefinitions) cycle(path []string) []string {
+	for _, ref := range d.references(path[len(path)-1]) {
+		if ref == path[0] {
+			return append(path, ref)
+		}
+		if _, defined := d[ref]; !defined || contains(path, ref) {
+			continue
+		}
+		if cycle := d.cycle(append(path, ref)); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Validate checks every definition and joins the problems found, in order of
+// name
+func (d MarkerDefinitions) Validate() error {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []error
+	for _, name := range names {
+		if err := d.Check(name); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// Expand returns what the marker with the given content, the text between
+// its parentheses, stands for, with defined markers expanded all the way to
+// built-in ones. It reports false if the marker is not defined.
+func (d MarkerDefinitions) Expand(content string) (string, bool) {
+	return d.expand(content, 0)
+}
+
+func (d MarkerDefinitions) expand(content string, depth int) (string, bool) {
+	name, count, numbered := strings.Cut(content, ",")
+	if _, defined := d[name]; !defined || depth > len(d) {
+		// Past the number of definitions there must be a cycle, which
+		// Check reports; the marker is then left as it is
+		return "", false
+	}
+	return markerReferenceRegex.ReplaceAllStringFunc(d.template(name), func(ref string) string {
+		m := markerReferenceRegex.FindStringSubmatch(ref)
+		inner := m[1]
+		switch {
+		case strings.Contains(ref, ","):
+			inner += "," + m[2]
+		case numbered:
+			inner += "," + count
+		}
+		if expanded, ok := d.expand(inner, depth+1); ok {
+			return expanded
+		}
+		return "(" + inner + ")"
+	}), true
+}
+
+func isBuiltinMarker(name string) bool {
+	return contains(MarkerNames, name)
+}
+
+// isWord reports whether s is a non-empty run of ASCII letters
+func isWord(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return !isASCIILetter(r) }) < 0
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
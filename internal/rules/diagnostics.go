@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Severity tells how serious a diagnostic is
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText encodes the severity by name, as in JSON
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic describes a problem found while applying rules. Line and
+// Column are 1-based and point into the input text; Column counts runes.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// String formats the diagnostic as "line:column: severity: message"
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// LineIndex converts byte offsets in a text to line and column numbers.
+// Diagnostics usually come in offset order, so it remembers the last
+// position and counts the runes from there when it can.
+type LineIndex struct {
+	text   string
+	starts []int // byte offset of the start of each line
+
+	// The last offset converted and its position
+	last, lastLine, lastColumn int
+}
+
+// NewLineIndex indexes the line starts of text
+func NewLineIndex(text string) *LineIndex {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &LineIndex{text: text, starts: starts, lastLine: -1}
+}
+
+// Position returns the 1-based line and column of offset
+func (l *LineIndex) Position(offset int) (int, int) {
+	line := sort.Search(len(l.starts), func(i int) bool { return l.starts[i] > offset }) - 1
+	from, column := l.starts[line], 1
+	if line == l.lastLine && l.last <= offset {
+		from, column = l.last, l.lastColumn
+	}
+	column += utf8.RuneCountInString(l.text[from:offset])
+	l.last, l.lastLine, l.lastColumn = offset, line, column
+	return line + 1, column
+}
+
+// Diagnostic builds a diagnostic for the given byte offset
+func (l *LineIndex) Diagnostic(offset int, severity Severity, format string, args ...interface{}) Diagnostic {
+	line, column := l.Position(offset)
+	return Diagnostic{Line: line, Column: column, Severity: severity, Message: fmt.Sprintf(format, args...)}
+}
+
+// SortDiagnostics sorts diagnostics by position, keeping the order of those
+// at the same position, and returns them
+func SortDiagnostics(diagnostics []Diagnostic) []Diagnostic {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+	return diagnostics
+}
@@ -0,0 +1,335 @@
+package rules
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CaseMarkers applies the case markers that wait for words written after
+// them: forward markers like (up>, 2), and ranges from (up:begin) to the
+// matching (up:end). Callers write text to Buffer as they scan it, report
+// each marker they find and call Flush before every other marker and at the
+// end, so markers apply in the order their last word is reached.
+type CaseMarkers struct {
+	Buffer *WordBuffer
+	// Scope limits how far forward markers reach
+	Scope Scope
+	// Warn is called with the offset of a forward marker and a message when
+	// the marker is clamped to its scope
+	Warn func(offset int, message string)
+
+	// forward holds the forward markers in the order they were found, so
+	// also in the order of their first words. Those before next whose
+	// scope has ended are all applied.
+	forward []waitingMarker
+	next    int
+	// byEnd holds the indexes into forward of the markers not applied yet,
+	// by the index of the word after their last word
+	byEnd endHeap
+	// boundaries holds the indexes of the words that start a new scope,
+	// found for the first scanned words
+	boundaries []int
+	scanned    int
+
+	ranges []waitingMarker
+}
+
+// waitingMarker is a marker and the index of the first word it applies to
+type waitingMarker struct {
+	marker  Marker
+	offset  int
+	word    int
+	applied bool
+}
+
+// endHeap is a min-heap of indexes into forward by where their words end
+type endHeap struct {
+	forward *[]waitingMarker
+	indexes []int
+}
+
+func (h endHeap) end(i int) int {
+	w := (*h.forward)[h.indexes[i]]
+	return w.word + w.marker.Count
+}
+
+func (h endHeap) Len() int            { return len(h.indexes) }
+func (h endHeap) Less(i, j int) bool  { return h.end(i) < h.end(j) }
+func (h endHeap) Swap(i, j int)       { h.indexes[i], h.indexes[j] = h.indexes[j], h.indexes[i] }
+func (h *endHeap) Push(x interface{}) { h.indexes = append(h.indexes, x.(int)) }
+func (h *endHeap) Pop() interface{} {
+	last := h.indexes[len(h.indexes)-1]
+	h.indexes = h.indexes[:len(h.indexes)-1]
+	return last
+}
+
+// Forward starts a forward marker found at offset, given the whitespace that
+// follows it in the text. The marker is dropped with the whitespace on one
+// side of it: Forward reports true if the caller should skip the whitespace
+// after it, and otherwise drops the whitespace before it.
+func (c *CaseMarkers) Forward(marker Marker, offset int, after string) bool {
+	c.forward = append(c.forward, waitingMarker{marker: marker, offset: offset, word: c.Buffer.WordCount()})
+	c.byEnd.forward = &c.forward
+	heap.Push(&c.byEnd, len(c.forward)-1)
+	return c.Buffer.DropSpace(after)
+}
+
+// Begin starts a range marker like (up:begin), dropping its spacing as
+// Forward does
+func (c *CaseMarkers) Begin(marker Marker, offset int, after string) bool {
+	c.ranges = append(c.ranges, waitingMarker{marker: marker, offset: offset, word: c.Buffer.WordCount()})
+	return c.Buffer.DropSpace(after)
+}
+
+// End closes the last open range with the name of marker, applying it to
+// the words since its start and dropping the end marker's spacing as
+// Forward does. It reports false, leaving the buffer alone, if no such
+// range is open.
+func (c *CaseMarkers) End(marker Marker, after string) bool {
+	for i := len(c.ranges) - 1; i >= 0; i-- {
+		if c.ranges[i].marker.Name != marker.Name {
+			continue
+		}
+		begin := c.ranges[i]
+		c.ranges = append(c.ranges[:i], c.ranges[i+1:]...)
+		skip := c.Buffer.DropSpace(after)
+		c.Buffer.TransformFrom(begin.word, c.Buffer.WordCount()-begin.word, caseTransforms[marker.Name])
+		c.Buffer.EndWord()
+		return skip
+	}
+	return false
+}
+
+// dropsSpaceAfter reports whether a marker removed from between the
+// whitespace before and after it takes the whitespace after it with it
+func dropsSpaceAfter(before, after string) bool {
+	return before == "" || after != "" && strings.Count(after, "\n") <= strings.Count(before, "\n")
+}
+
+// RemoveMarkers removes the markers at spans, given as start and end
+// offsets in the order they appear in text, each together with the
+// whitespace on one side of it, as forward markers are removed. A marker
+// written straight after or before a word is removed alone, so the words
+// around it stay apart.
+func RemoveMarkers(text string, spans [][2]int) string {
+	var result strings.Builder
+	result.Grow(len(text))
+	// space is the whitespace at the end of the result, held back until
+	// more text follows so a marker can drop it
+	space := ""
+	write := func(s string) {
+		trimmed := strings.TrimRight(s, " \t\n\r")
+		if trimmed == "" {
+			space += s
+			return
+		}
+		result.WriteString(space)
+		result.WriteString(trimmed)
+		space = s[len(trimmed):]
+	}
+
+	last := 0
+	for i := 0; i < len(spans); i++ {
+		start, end := spans[i][0], spans[i][1]
+		write(text[last:start])
+		last = end
+		if start > 0 && !isSpaceByte(text[start-1]) {
+			continue
+		}
+		// Markers written straight after this one go with it
+		for i+1 < len(spans) && spans[i+1][0] == last {
+			i++
+			last = spans[i][1]
+		}
+		if last < len(text) && isWordByte(text[last]) {
+			continue
+		}
+		if after := LeadingSpace(text[last:]); dropsSpaceAfter(space, after) {
+			last += len(after)
+		} else {
+			space = ""
+		}
+	}
+	write(text[last:])
+	result.WriteString(space)
+	return result.String()
+}
+
+// Flush applies the forward markers whose words have all been written. At
+// the end of the text, final applies the rest to the words they have.
+// Markers apply in the order their last word is reached, or their scope or
+// the text ends, and markers reaching it together in the order they were
+// found, so how often Flush is called does not change the result.
+func (c *CaseMarkers) Flush(final bool) {
+	c.scanBoundaries()
+	words := c.Buffer.WordCount()
+	var ready []int
+	for c.byEnd.Len() > 0 && (final || c.byEnd.end(0) <= words) {
+		ready = append(ready, heap.Pop(&c.byEnd).(int))
+	}
+	// A marker whose scope ended before its last word applies to the words
+	// it has
+	if n := len(c.boundaries); n > 0 {
+		for ; c.next < len(c.forward) && c.forward[c.next].word < c.boundaries[n-1]; c.next++ {
+			ready = append(ready, c.next)
+		}
+	}
+	sort.Slice(ready, func(a, b int) bool {
+		atA, atB := c.readyAt(ready[a]), c.readyAt(ready[b])
+		return atA < atB || atA == atB && ready[a] < ready[b]
+	})
+
+	for _, i := range ready {
+		w := &c.forward[i]
+		if w.applied {
+			continue
+		}
+		w.applied = true
+		n := c.wordsFrom(w.word)
+		if n < w.marker.Count && c.Warn != nil {
+			c.Warn(w.offset, c.clampWarning(w.marker, n))
+		}
+		c.Buffer.TransformFrom(w.word, min(n, w.marker.Count), caseTransforms[w.marker.Name])
+	}
+	if c.byEnd.Len() == 0 {
+		// Nothing waits, so start again with the next marker
+		c.forward, c.next = c.forward[:0], 0
+	}
+}
+
+// scanBoundaries finds the scope boundaries before the words written since
+// the last call. Words already written do not change where scopes start.
+func (c *CaseMarkers) scanBoundaries() {
+	words := c.Buffer.WordCount()
+	if c.Scope == ScopeUnbounded {
+		c.scanned = words
+		return
+	}
+	for ; c.scanned < words; c.scanned++ {
+		if c.scanned > 0 && c.Buffer.boundaryBefore(c.scanned, c.Scope) {
+			c.boundaries = append(c.boundaries, c.scanned)
+		}
+	}
+}
+
+// readyAt orders the forward marker at index i of forward by when it can
+// apply: after its last word, or after the word starting the next scope if
+// that comes first, which is only known once that word is written. It
+// returns twice the index of the word after the last one it waits for, plus
+// one if it waits for the next scope.
+func (c *CaseMarkers) readyAt(i int) int {
+	w := c.forward[i]
+	end := w.word + w.marker.Count
+	if j := sort.SearchInts(c.boundaries, w.word+1); j < len(c.boundaries) && c.boundaries[j] < end {
+		return 2*c.boundaries[j] + 1
+	}
+	return 2 * end
+}
+
+// wordsFrom returns the number of words written from the word at index
+// start that are in the same scope as it
+func (c *CaseMarkers) wordsFrom(start int) int {
+	end := c.Buffer.WordCount()
+	if i := sort.SearchInts(c.boundaries, start+1); i < len(c.boundaries) {
+		end = c.boundaries[i]
+	}
+	return max(end-start, 0)
+}
+
+// clampWarning explains that a forward marker found only n of its words
+func (c *CaseMarkers) clampWarning(marker Marker, n int) string {
+	unit := c.Scope.String()
+	if c.Scope == ScopeUnbounded {
+		unit = "text"
+	}
+	if n == 0 {
+		return fmt.Sprintf("%s has no words after it in its %s; dropped", marker, unit)
+	}
+	return fmt.Sprintf("%s reaches past the end of its %s; applied to %d words", marker, unit, n)
+}
+
+// OpensForward reports whether the forward or range begin marker from start
+// to end in text is placed so it can apply: at the start of the text or
+// after whitespace, and followed by whitespace and more text
+func OpensForward(text string, start, end int) bool {
+	return (start == 0 || isSpaceByte(text[start-1])) &&
+		end < len(text) && isSpaceByte(text[end]) && strings.TrimSpace(text[end:]) != ""
+}
+
+// ClosesBackward reports whether the backward or range end marker from
+// start to end in text is placed so it can apply: after whitespace, and not
+// followed by a word it would be glued to once it is removed
+func ClosesBackward(text string, start, end int) bool {
+	return start > 0 && isSpaceByte(text[start-1]) && (end == len(text) || !isWordByte(text[end]))
+}
+
+// WaitsForWords reports whether text ends with case markers that text after
+// it could still apply to: a forward marker like (up>, 3) followed by fewer
+// words than its count, or a (name:begin) with no (name:end) after it
+func WaitsForWords(text string) bool {
+	matches := caseMarkerRegex.FindAllStringSubmatchIndex(text, -1)
+	markers := make([]Marker, len(matches))
+	// Count the words before the end of each marker as WordBuffer does, so
+	// markers are not words
+	var words WordBuffer
+	last := 0
+	wordsBefore := make([]int, len(matches))
+	for i, m := range matches {
+		words.Write(text[last:m[1]])
+		last = m[1]
+		wordsBefore[i] = words.WordCount()
+	}
+	words.Write(text[last:])
+	for i, m := range matches {
+		marker, err := ParseMarker(text[m[0]+1 : m[1]-1])
+		if err != nil {
+			continue
+		}
+		if marker.Direction == Forward && words.WordCount()-wordsBefore[i] < marker.Count {
+			return true
+		}
+		markers[i] = marker
+	}
+	for i, partner := range MatchRanges(markers) {
+		if markers[i].Direction == RangeBegin && partner < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LeadingSpace returns the whitespace at the start of text
+func LeadingSpace(text string) string {
+	end := 0
+	for end < len(text) && isSpaceByte(text[end]) {
+		end++
+	}
+	return text[:end]
+}
+
+// MatchRanges pairs up the range markers among markers, given in the order
+// they appear. Each (name:end) closes the nearest unclosed (name:begin)
+// before it. It returns the partner of each marker, or -1 for markers that
+// are not paired range markers.
+func MatchRanges(markers []Marker) []int {
+	partners := make([]int, len(markers))
+	var open []int
+	for i, m := range markers {
+		partners[i] = -1
+		switch m.Direction {
+		case RangeBegin:
+			open = append(open, i)
+		case RangeEnd:
+			for j := len(open) - 1; j >= 0; j-- {
+				if markers[open[j]].Name == m.Name {
+					partners[i], partners[open[j]] = open[j], i
+					open = append(open[:j], open[j+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return partners
+}
@@ -0,0 +1,146 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Locale selects the typographic conventions for punctuation and quotes
+type Locale int
+
+const (
+	// LocaleEnglish attaches all punctuation to the word before it
+	LocaleEnglish Locale = iota
+	// LocaleFrench puts a narrow no-break space before ;:!? and inside
+	// « guillemets »
+	LocaleFrench
+	// LocaleGerman cleans spaces inside „quotes“ and »guillemets«
+	LocaleGerman
+	// LocaleSpanish attaches ¿ and ¡ to the word after them and cleans
+	// spaces inside «guillemets»
+	LocaleSpanish
+)
+
+var localeNames = map[Locale]string{
+	LocaleEnglish: "en",
+	LocaleFrench:  "fr",
+	LocaleGerman:  "de",
+	LocaleSpanish: "es",
+}
+
+// String returns the name of the locale
+func (l Locale) String() string {
+	return localeNames[l]
+}
+
+// ParseLocale returns the locale with the given name
+func ParseLocale(name string) (Locale, error) {
+	for locale, localeName := range localeNames {
+		if localeName == name {
+			return locale, nil
+		}
+	}
+	return LocaleEnglish, fmt.Errorf("invalid locale %q. Use one of [en|fr|de|es]", name)
+}
+
+// NarrowNoBreakSpace is the space French typography puts before ;:!? and
+// inside guillemets
+const NarrowNoBreakSpace = '\u202F'
+
+// quotePair is a pair of opening and closing quote marks together with the
+// space kept just inside them
+type quotePair struct {
+	open, close rune
+	inner       string
+	regex       *regexp.Regexp
+}
+
+func newQuotePair(open, close rune, inner string) quotePair {
+	space := "[ \\t\u00A0\u202F]*"
+	pattern := regexp.QuoteMeta(string(open)) + space + "([^" + string(open) + string(close) + "\\n]*?)" + space + regexp.QuoteMeta(string(close))
+	return quotePair{open: open, close: close, inner: inner, regex: regexp.MustCompile(pattern)}
+}
+
+// localeProfile holds the rules of a locale that differ from English
+type localeProfile struct {
+	// punctuation overrides entries of PunctuationRules
+	punctuation map[rune]PunctuationRule
+	// quotes are cleaned in addition to single quotes
+	quotes []quotePair
+}
+
+var localeProfiles = map[Locale]localeProfile{
+	LocaleFrench: {
+		punctuation: map[rune]PunctuationRule{
+			';': {NarrowSpaceBefore: true},
+			':': {NarrowSpaceBefore: true},
+			'!': {NarrowSpaceBefore: true},
+			'?': {NarrowSpaceBefore: true},
+			'‽': {NarrowSpaceBefore: true},
+		},
+		quotes: []quotePair{newQuotePair('«', '»', string(NarrowNoBreakSpace))},
+	},
+	LocaleGerman: {
+		quotes: []quotePair{newQuotePair('„', '“', ""), newQuotePair('»', '«', "")},
+	},
+	LocaleSpanish: {
+		punctuation: map[rune]PunctuationRule{
+			'¿': {AttachNext: true},
+			'¡': {AttachNext: true},
+		},
+		quotes: []quotePair{newQuotePair('«', '»', "")},
+	},
+}
+
+// localeTables holds the full punctuation table of every locale
+var localeTables = buildLocaleTables()
+
+func buildLocaleTables() map[Locale]map[rune]PunctuationRule {
+	tables := make(map[Locale]map[rune]PunctuationRule, len(localeNames))
+	for locale := range localeNames {
+		table := make(map[rune]PunctuationRule, len(PunctuationRules))
+		for r, rule := range PunctuationRules {
+			table[r] = rule
+		}
+		for r, rule := range localeProfiles[locale].punctuation {
+			table[r] = rule
+		}
+		tables[locale] = table
+	}
+	return tables
+}
+
+// punctuationTable returns the punctuation table of locale
+func punctuationTable(locale Locale) map[rune]PunctuationRule {
+	if table, ok := localeTables[locale]; ok {
+		return table
+	}
+	return PunctuationRules
+}
+
+// IsQuote reports whether r is a quote mark in any locale
+func IsQuote(r rune) bool {
+	if r == '\'' || r == '"' {
+		return true
+	}
+	for _, profile := range localeProfiles {
+		for _, pair := range profile.quotes {
+			if r == pair.open || r == pair.close {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanLocaleQuotes sets the space inside the quote marks of locale
+func cleanLocaleQuotes(text string, locale Locale) string {
+	for _, pair := range localeProfiles[locale].quotes {
+		if !strings.ContainsRune(text, pair.open) {
+			continue
+		}
+		text = pair.regex.ReplaceAllString(text, string(pair.open)+pair.inner+"${1}"+pair.inner+string(pair.close))
+	}
+	return text
+}
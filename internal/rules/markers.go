@@ -0,0 +1,281 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// caseTransforms maps case marker names to the transformation they apply
+var caseTransforms = map[string]func(string) string{
+	"up":  strings.ToUpper,
+	"low": strings.ToLower,
+	"cap": capitalize,
+}
+
+// numberBases maps number marker names to the base they convert from
+var numberBases = map[string]int{
+	"hex": 16,
+	"bin": 2,
+}
+
+// MarkerNames lists the marker names in the order they are documented
+var MarkerNames = []string{"hex", "bin", "up", "low", "cap"}
+
+// MarkerDescriptions describes what each marker does to the word before it
+var MarkerDescriptions = map[string]string{
+	"hex": "converts a hexadecimal number to decimal",
+	"bin": "converts a binary number to decimal",
+	"up":  "converts to uppercase",
+	"low": "converts to lowercase",
+	"cap": "capitalizes",
+}
+
+// Direction says which words a marker applies to
+type Direction int
+
+const (
+	// Backward markers like (up, 2) apply to the words before them
+	Backward Direction = iota
+	// Forward markers like (up>, 2) apply to the words after them
+	Forward
+	// RangeBegin markers like (up:begin) apply to the words up to the
+	// matching RangeEnd marker, (up:end)
+	RangeBegin
+	RangeEnd
+)
+
+// directionSuffixes is what follows the marker name for each direction
+var directionSuffixes = map[Direction]string{
+	Forward:    ">",
+	RangeBegin: ":begin",
+	RangeEnd:   ":end",
+}
+
+// Marker is a parsed marker like (up), (cap, 3), (low>, 2) or (up:begin)
+type Marker struct {
+	Name string
+	// Count is the number of words the marker applies to
+	Count     int
+	Numbered  bool
+	Direction Direction
+}
+
+// String returns the marker as it is written
+func (m Marker) String() string {
+	name := m.Name + directionSuffixes[m.Direction]
+	if m.Numbered {
+		return fmt.Sprintf("(%s, %d)", name, m.Count)
+	}
+	return "(" + name + ")"
+}
+
+// splitDirection splits a marker name like "up>" or "up:begin" into the
+// name and its direction. The suffix is "" if it is not a known one.
+func splitDirection(name string) (string, Direction, string) {
+	if strings.HasSuffix(name, ">") {
+		return name[:len(name)-1], Forward, ">"
+	}
+	if base, suffix, ok := strings.Cut(name, ":"); ok && isWord(suffix) {
+		for direction, s := range directionSuffixes {
+			if s == ":"+suffix {
+				return base, direction, s
+			}
+		}
+		return base, Backward, ":" + suffix
+	}
+	return name, Backward, ""
+}
+
+// ErrNotMarker is returned by ParseMarker for parenthesised text that is
+// not meant as a marker, like "(see below)"
+var ErrNotMarker = errors.New("not a marker")
+
+// MarkerError describes parenthesised text that is meant as a marker but is
+// malformed or has an unknown name, so it is left as text
+type MarkerError struct {
+	// Content is the text between the parentheses
+	Content string
+	// Unknown is set when the name is not a marker name
+	Unknown bool
+	// Suggestion is the marker the content was probably meant to be, or ""
+	Suggestion string
+	Reason     string
+}
+
+func (e *MarkerError) Error() string {
+	kind := "malformed"
+	if e.Unknown {
+		kind = "unknown"
+	}
+	message := fmt.Sprintf("%s marker (%s)", kind, e.Content)
+	if e.Reason != "" {
+		message += ": " + e.Reason
+	}
+	if e.Suggestion != "" {
+		message += "; did you mean " + e.Suggestion + "?"
+	}
+	return message
+}
+
+// ParseMarker parses content, the text between a pair of parentheses, as it
+// is applied by the pipeline. It returns ErrNotMarker for text that does not
+// look like a marker and a *MarkerError for near misses.
+func ParseMarker(content string) (Marker, error) {
+	namePart, countPart, numbered := strings.Cut(content, ",")
+	name, direction, suffix := splitDirection(strings.TrimSpace(namePart))
+	if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isASCIILetter(r) }) >= 0 {
+		return Marker{}, ErrNotMarker
+	}
+
+	_, isCase := caseTransforms[name]
+	_, isNumber := numberBases[name]
+	if !isCase && !isNumber {
+		// Only names close to a marker name are taken as markers, so
+		// text like (Smith, 2003) is left alone
+		suggestion := SuggestMarker(name)
+		if suggestion == "" {
+			return Marker{}, ErrNotMarker
+		}
+		err := &MarkerError{Content: content, Unknown: true, Suggestion: "(" + suggestion + ")"}
+		if m, e := ParseMarker(strings.Replace(content, name, suggestion, 1)); e == nil {
+			err.Suggestion = m.String()
+		}
+		return Marker{}, err
+	}
+
+	marker := Marker{Name: name, Count: 1, Direction: direction}
+	switch {
+	case suffix != "" && directionSuffixes[direction] != suffix:
+		return Marker{}, &MarkerError{Content: content, Reason: "a range marker ends in :begin or :end"}
+	case isNumber && direction != Backward:
+		marker.Direction = Backward
+		return Marker{}, &MarkerError{Content: content, Suggestion: marker.String(), Reason: "only case markers can point forward or mark a range"}
+	}
+	if numbered {
+		if isNumber || direction == RangeBegin || direction == RangeEnd {
+			return Marker{}, &MarkerError{Content: content, Suggestion: marker.String(), Reason: "it takes no count"}
+		}
+		count := strings.TrimSpace(countPart)
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 || strings.IndexFunc(count, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return Marker{}, &MarkerError{Content: content, Reason: "the count must be a whole number of at least 1"}
+		}
+		marker.Count, marker.Numbered = n, true
+	}
+
+	// The pipeline only allows spaces after the comma
+	if strings.TrimSpace(namePart) != namePart || numbered && strings.TrimRight(countPart, " \t\n\r") != countPart {
+		return Marker{}, &MarkerError{Content: content, Suggestion: marker.String(), Reason: "extra spaces"}
+	}
+	return marker, nil
+}
+
+// SuggestMarker returns the marker name that name is probably a typo of, or
+// "" if there is none
+func SuggestMarker(name string) string {
+	lower := strings.ToLower(name)
+	for _, candidate := range MarkerNames {
+		if lower == candidate {
+			return candidate
+		}
+	}
+	if len(name) < 2 {
+		return ""
+	}
+	for _, candidate := range MarkerNames {
+		if editDistance(lower, candidate) <= 1 {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent letters needed to turn a into b
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// TakesCount reports whether the named marker has a numbered form like
+// (up, 3). Only case markers do.
+func TakesCount(name string) bool {
+	_, ok := caseTransforms[name]
+	return ok
+}
+
+// TransformWord applies a single-word marker (hex, bin, up, low or cap) to
+// word. It reports false if the marker is unknown or, for hex and bin, if the
+// word is not a valid number.
+func TransformWord(marker, word string) (string, bool) {
+	if fn, ok := caseTransforms[marker]; ok {
+		return fn(word), true
+	}
+	if base, ok := numberBases[marker]; ok {
+		return convertNumber(word, base)
+	}
+	return word, false
+}
+
+// looksLikeMarker reports whether content, the text between a pair of
+// parentheses, would be read as a marker by any processor
+func looksLikeMarker(content string) bool {
+	name, count, numbered := strings.Cut(content, ",")
+	name, direction, suffix := splitDirection(strings.TrimSpace(name))
+	_, isCase := caseTransforms[name]
+	_, isNumber := numberBases[name]
+	if suffix != directionSuffixes[direction] {
+		return false
+	}
+	if direction != Backward {
+		_, err := strconv.Atoi(strings.TrimSpace(count))
+		return isCase && (!numbered || direction == Forward && err == nil)
+	}
+	if !numbered {
+		return isCase || isNumber
+	}
+	_, err := strconv.Atoi(strings.TrimSpace(count))
+	return isCase && err == nil
+}
+
+// capitalize title-cases word, but doesn't override already uppercase words
+func capitalize(word string) string {
+	if word == strings.ToUpper(word) && len(word) > 1 {
+		return word
+	}
+	return strings.Title(strings.ToLower(word))
+}
+
+// convertNumber converts word from base to decimal
+func convertNumber(word string, base int) (string, bool) {
+	val, err := strconv.ParseInt(word, base, 64)
+	if err != nil {
+		return word, false
+	}
+	return strconv.FormatInt(val, 10), true
+}
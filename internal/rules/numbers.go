@@ -1,42 +1,95 @@
 package rules
 
 import (
-	"regexp"
-	"strconv"
 	"strings"
 )
 
 // ApplyNumbers processes hex and bin conversions
 func ApplyNumbers(text string) string {
-	// Handle (hex) conversions
-	hexRegex := regexp.MustCompile(`(\w+)\s+\(hex\)`)
-	text = hexRegex.ReplaceAllStringFunc(text, func(match string) string {
-		parts := strings.Fields(match)
-		if len(parts) >= 2 {
-			hexStr := parts[0]
-			if val, err := strconv.ParseInt(hexStr, 16, 64); err == nil {
-				return strconv.FormatInt(val, 10)
-			}
-			// Invalid hex - remove the (hex) marker
-			return hexStr
+	return replaceNumbers(text)
+}
+
+// replaceNumbers converts the word before each number marker from the
+// marker's base to decimal, left to right, so a marker right after another
+// converts the number the first one gave. The word must be separated from
+// the marker by whitespace, and the marker from any word after it. Markers
+// after invalid numbers are kept as text.
+func replaceNumbers(text string) string {
+	var result []byte
+	last := 0
+	for next := 0; ; {
+		i := strings.IndexByte(text[next:], '(')
+		if i < 0 {
+			break
+		}
+		i += next
+		next = i + 1
+		base := 0
+		switch {
+		case strings.HasPrefix(text[i:], "(hex)"):
+			base = 16
+		case strings.HasPrefix(text[i:], "(bin)"):
+			base = 2
+		default:
+			continue
 		}
-		return match
-	})
+		end := i + len("(hex)")
+		next = end
 
-	// Handle (bin) conversions
-	binRegex := regexp.MustCompile(`(\w+)\s+\(bin\)`)
-	text = binRegex.ReplaceAllStringFunc(text, func(match string) string {
-		parts := strings.Fields(match)
-		if len(parts) >= 2 {
-			binStr := parts[0]
-			if val, err := strconv.ParseInt(binStr, 2, 64); err == nil {
-				return strconv.FormatInt(val, 10)
-			}
-			// Invalid bin - remove the (bin) marker
-			return binStr
+		// Walk back over the whitespace and the word before the marker,
+		// which may be a number converted by the marker before
+		result = append(result, text[last:i]...)
+		last = end
+		wordEnd := len(result)
+		for wordEnd > 0 && isSpaceByte(result[wordEnd-1]) {
+			wordEnd--
+		}
+		start := wordEnd
+		for start > 0 && isWordByte(result[start-1]) {
+			start--
+		}
+		if wordEnd == len(result) || start == wordEnd || end < len(text) && isWordByte(text[end]) {
+			result = append(result, text[i:end]...)
+			continue
+		}
+
+		word, ok := convertNumber(string(result[start:wordEnd]), base)
+		if !ok {
+			result = append(result, text[i:end]...)
+			continue
+		}
+		// Drop the whitespace on one side of the marker, as the case
+		// markers do
+		space := string(result[wordEnd:])
+		result = append(result[:start], word...)
+		if after := LeadingSpace(text[end:]); dropsSpaceAfter(space, after) {
+			result = append(result, space...)
+			last += len(after)
 		}
-		return match
-	})
+	}
+	if last == 0 {
+		return text
+	}
+	result = append(result, text[last:]...)
+	return string(result)
+}
 
-	return text
-}
\ No newline at end of file
+// ConvertLastNumber converts the number at the end of word from the base of
+// the named number marker to decimal, taking the letters, digits and
+// underscores ApplyNumbers takes before a marker. It reports false, leaving
+// word alone, if there is no valid number there.
+func ConvertLastNumber(marker, word string) (string, bool) {
+	base, ok := numberBases[marker]
+	start := len(word)
+	for start > 0 && isWordByte(word[start-1]) {
+		start--
+	}
+	if !ok || start == len(word) {
+		return word, false
+	}
+	converted, ok := convertNumber(word[start:], base)
+	if !ok {
+		return word, false
+	}
+	return word[:start] + converted, true
+}
@@ -0,0 +1,19 @@
+package rules
+
+// Options configures how the rules are applied. The zero value gives the
+// default behaviour of the plain rule functions.
+type Options struct {
+	// Scope limits how far back numbered markers can reach
+	Scope Scope
+	// Whitespace is the policy applied to the whitespace of the result
+	Whitespace Whitespace
+	// Locale selects the punctuation and quote conventions
+	Locale Locale
+	// Articles adds words to the built-in article exceptions
+	Articles ArticleExceptions
+	// Markers defines extra markers in terms of the built-in ones
+	Markers MarkerDefinitions
+	// MarkerPolicy says what happens to unknown, malformed and misplaced
+	// markers
+	MarkerPolicy MarkerPolicy
+}
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// numberNames names the base of each number marker in messages
+var numberNames = map[string]string{
+	"hex": "hexadecimal",
+	"bin": "binary",
+}
+
+// PlacedMarker is a well-formed marker and where it is in a text. Start and
+// End are the byte offsets of the "(" and just past the ")".
+type PlacedMarker struct {
+	Marker
+	Start, End int
+}
+
+// MarkerProblems explains why each of markers, given in the order they
+// appear in text, is left as text by the rules instead of being applied, or
+// gives "" for the markers that apply. Markers with no Name are skipped.
+func MarkerProblems(text string, markers []PlacedMarker) []string {
+	problems := make([]string, len(markers))
+	placed := make([]Marker, len(markers))
+	converted := make(map[int]string)
+	words, last := false, 0
+	for i, m := range markers {
+		if m.Name == "" {
+			continue
+		}
+		words = words || hasWord(text[last:m.Start])
+		last = m.End
+		if problems[i] = placementProblem(text, m, words, converted); problems[i] == "" {
+			placed[i] = m.Marker
+		}
+	}
+
+	// Only well-placed range markers take part in pairing
+	for i, partner := range MatchRanges(placed) {
+		if partner >= 0 {
+			continue
+		}
+		written := text[markers[i].Start:markers[i].End]
+		switch placed[i].Direction {
+		case RangeBegin:
+			problems[i] = fmt.Sprintf("marker %s has no matching (%s:end) after it", written, placed[i].Name)
+		case RangeEnd:
+			problems[i] = fmt.Sprintf("marker %s has no matching (%s:begin) before it", written, placed[i].Name)
+		}
+	}
+	return problems
+}
+
+// placementProblem explains why m cannot apply where it is, leaving range
+// pairing aside, or returns "". words tells whether text has words before
+// m, outside markers. converted holds the numbers given by the
+// number markers before m that apply, keyed by the offset of their end; a
+// number marker that applies adds its own.
+func placementProblem(text string, m PlacedMarker, words bool, converted map[int]string) string {
+	written := text[m.Start:m.End]
+	if m.Start > 0 && !isSpaceByte(text[m.Start-1]) {
+		return fmt.Sprintf("marker %s must be separated from the word before it by a space", written)
+	}
+	switch m.Direction {
+	case Forward, RangeBegin:
+		if !OpensForward(text, m.Start, m.End) {
+			return fmt.Sprintf("marker %s must be followed by a space and the words it applies to", written)
+		}
+		return ""
+	}
+	if m.End < len(text) && isWordByte(text[m.End]) {
+		return fmt.Sprintf("marker %s must be separated from the word after it", written)
+	}
+	if m.Direction == RangeEnd {
+		return ""
+	}
+
+	if !words {
+		return fmt.Sprintf("marker %s has no word before it", written)
+	}
+	base, ok := numberBases[m.Name]
+	if !ok {
+		return ""
+	}
+	word := lastNumberWord(strings.TrimRight(text[:m.Start], " \t\n\r\f\v"), converted)
+	if word == "" {
+		return fmt.Sprintf("marker %s has no %s number before it", written, numberNames[m.Name])
+	}
+	number, ok := convertNumber(word, base)
+	if !ok {
+		return fmt.Sprintf("marker %s cannot convert %q, which is not a %s number", written, word, numberNames[m.Name])
+	}
+	converted[m.End] = number
+	return ""
+}
+
+// hasWord reports whether text has a word outside the markers in it, as
+// WordBuffer counts words
+func hasWord(text string) bool {
+	for len(text) > 0 {
+		start, end := nextMarker(text)
+		if strings.TrimLeft(text[:start], " \t\n\r\f\v") != "" {
+			return true
+		}
+		text = text[end:]
+	}
+	return false
+}
+
+// lastNumberWord returns the word a number marker after text converts. Case
+// markers in between are applied first, so they are skipped, and a number
+// marker that applies gives the number it converted, from converted.
+func lastNumberWord(text string, converted map[int]string) string {
+	for strings.HasSuffix(text, ")") {
+		if number, ok := converted[len(text)]; ok {
+			return number
+		}
+		open := strings.LastIndexByte(text, '(')
+		if open < 0 {
+			break
+		}
+		marker, err := ParseMarker(text[open+1 : len(text)-1])
+		if err != nil || marker.Direction != Backward || !TakesCount(marker.Name) {
+			break
+		}
+		text = strings.TrimRight(text[:open], " \t\n\r\f\v")
+	}
+	start := len(text)
+	for start > 0 && isWordByte(text[start-1]) {
+		start--
+	}
+	return text[start:]
+}
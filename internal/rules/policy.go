@@ -0,0 +1,48 @@
+package rules
+
+import "fmt"
+
+// MarkerPolicy says what happens to unknown and malformed markers, like
+// (upp) or (cap, -2), and to markers that cannot apply where they are, like
+// (hex) after a word that is not a number, which the rules leave as text
+type MarkerPolicy int
+
+const (
+	// MarkersKeep leaves them in the text and warns about them
+	MarkersKeep MarkerPolicy = iota
+	// MarkersStrip removes them from the text and warns about them
+	MarkersStrip
+	// MarkersError leaves them in the text and reports them as errors, so
+	// callers can refuse the result
+	MarkersError
+)
+
+var markerPolicyNames = map[MarkerPolicy]string{
+	MarkersKeep:  "keep",
+	MarkersStrip: "strip",
+	MarkersError: "error",
+}
+
+// String returns the name of the policy
+func (p MarkerPolicy) String() string {
+	return markerPolicyNames[p]
+}
+
+// ParseMarkerPolicy returns the marker policy with the given name
+func ParseMarkerPolicy(name string) (MarkerPolicy, error) {
+	for policy, policyName := range markerPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return MarkersKeep, fmt.Errorf("invalid marker policy %q. Use one of [keep|strip|error]", name)
+}
+
+// Severity returns the severity of the diagnostics for markers the policy
+// applies to
+func (p MarkerPolicy) Severity() Severity {
+	if p == MarkersError {
+		return Error
+	}
+	return Warning
+}
@@ -2,25 +2,281 @@ package rules
 
 import (
 	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// PunctuationRule describes how text around a punctuation mark is spaced
+type PunctuationRule struct {
+	// Attach removes whitespace before the mark, as in "Hi !" -> "Hi!"
+	Attach bool
+	// SpaceAfter adds a space after the mark when text follows directly
+	SpaceAfter bool
+	// KeepBetweenDigits leaves the mark alone between two digits, as in
+	// 1,000, 3.14 or 10:30
+	KeepBetweenDigits bool
+	// Spaced puts exactly one space on each side of the mark within a line
+	Spaced bool
+	// ClosedBetweenDigits removes the spaces around the mark between two
+	// digits, as in the range 10–20
+	ClosedBetweenDigits bool
+	// Opens and Closes mark brackets, which take no spaces inside
+	Opens  bool
+	Closes bool
+	// NarrowSpaceBefore puts a narrow no-break space between the mark and
+	// the word before it, as in French "Bonjour !"
+	NarrowSpaceBefore bool
+	// AttachNext removes whitespace after the mark, as with the Spanish ¿
+	AttachNext bool
+}
+
+// PunctuationRules is the table of punctuation marks and their spacing in
+// English. Locales override some of its entries. The tokenizer uses the same
+// tables to decide what a Punctuation token is.
+var PunctuationRules = map[rune]PunctuationRule{
+	'.': {Attach: true, KeepBetweenDigits: true},
+	',': {Attach: true, SpaceAfter: true, KeepBetweenDigits: true},
+	':': {Attach: true, KeepBetweenDigits: true},
+	';': {Attach: true},
+	'!': {Attach: true},
+	'?': {Attach: true},
+	'…': {Attach: true},
+	'‽': {Attach: true},
+	'—': {Spaced: true},
+	'–': {Spaced: true, ClosedBetweenDigits: true},
+	'(': {Opens: true},
+	')': {Closes: true},
+	'[': {Opens: true},
+	']': {Closes: true},
+}
+
+// IsPunctuation reports whether r is in the punctuation table of any locale
+func IsPunctuation(r rune) bool {
+	for _, table := range localeTables {
+		if _, ok := table[r]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ellipsisRegex  = regexp.MustCompile(`\.\s*\.\s*\.`)
+	questExclRegex = regexp.MustCompile(`\?\s*!`)
+	bracketRegex   = regexp.MustCompile(`\(([^()\n]*)\)|\[([^\[\]\n]*)\]`)
 )
 
 // FixPunctuation fixes spacing around punctuation marks
 func FixPunctuation(text string) string {
-	// Remove spaces before punctuation marks and add space after comma
-	punctRegex := regexp.MustCompile(`\s+([.,:;!?]+)`)
-	text = punctRegex.ReplaceAllString(text, "$1")
-	
-	// Add space after comma if not followed by space
-	commaRegex := regexp.MustCompile(`,([^\s])`)
-	text = commaRegex.ReplaceAllString(text, ", $1")
-	
+	return FixPunctuationWithOptions(text, Options{})
+}
+
+// FixPunctuationWithOptions fixes spacing around punctuation marks following
+// the conventions of opts.Locale
+func FixPunctuationWithOptions(text string, opts Options) string {
+	table := punctuationTable(opts.Locale)
+
+	// Remove spaces before punctuation marks
+	text = removeSpaceBeforePunctuation(text, table)
+
+	// Add space after marks like the comma if not followed by space
+	text = addSpaceAfterPunctuation(text, table)
+
+	// Set narrow spaces before marks like the French ; and attach marks like
+	// the Spanish ¿ to the next word
+	text = narrowSpaceBeforePunctuation(text, table)
+	text = removeSpaceAfterPunctuation(text, table)
+
+	// Space dashes and close up ranges
+	text = spaceDashes(text, table)
+
+	// Remove spaces inside brackets
+	text = trimBrackets(text)
+
 	// Handle ellipsis and multiple punctuation
-	ellipsisRegex := regexp.MustCompile(`\.\s*\.\s*\.`)
 	text = ellipsisRegex.ReplaceAllString(text, "...")
-	
+
 	// Handle ?! combinations
-	questExclRegex := regexp.MustCompile(`\?\s*!`)
 	text = questExclRegex.ReplaceAllString(text, "?!")
-	
+
 	return text
-}
\ No newline at end of file
+}
+
+// removeSpaceBeforePunctuation drops every whitespace run that is directly
+// followed by an attaching punctuation mark
+func removeSpaceBeforePunctuation(text string, table map[rune]PunctuationRule) string {
+	var result strings.Builder
+	result.Grow(len(text))
+	i := 0
+	for i < len(text) {
+		if !isSpaceByte(text[i]) {
+			result.WriteByte(text[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(text) && isSpaceByte(text[end]) {
+			end++
+		}
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if !table[next].Attach {
+			result.WriteString(text[i:end])
+		}
+		i = end
+	}
+	return result.String()
+}
+
+// addSpaceAfterPunctuation adds a space after marks that need one when other
+// text follows, except between digits for marks like the comma in 1,000 so
+// a second run leaves them alone
+func addSpaceAfterPunctuation(text string, table map[rune]PunctuationRule) string {
+	var result strings.Builder
+	result.Grow(len(text) + len(text)/16)
+	prev := rune(0)
+	for i, r := range text {
+		result.WriteRune(r)
+		rule := table[r]
+		end := i + utf8.RuneLen(r)
+		if rule.SpaceAfter && end < len(text) && !isSpaceByte(text[end]) {
+			if !(rule.KeepBetweenDigits && isDigitRune(prev) && isDigitByte(text[end])) {
+				result.WriteByte(' ')
+			}
+		}
+		prev = r
+	}
+	return result.String()
+}
+
+// spaceDashes puts one space on each side of spaced marks like the em dash.
+// Spacing that includes a line break is left alone, and ranges between
+// digits are closed up for marks like the en dash.
+func spaceDashes(text string, table map[rune]PunctuationRule) string {
+	if !strings.ContainsAny(text, "—–") {
+		return text
+	}
+
+	out := make([]byte, 0, len(text)+len(text)/16)
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		rule := table[r]
+		if !rule.Spaced {
+			out = append(out, text[i:i+size]...)
+			i += size
+			continue
+		}
+
+		// Find the horizontal spacing on each side of the dash
+		before := len(out)
+		for before > 0 && (out[before-1] == ' ' || out[before-1] == '\t') {
+			before--
+		}
+		after := i + size
+		for after < len(text) && (text[after] == ' ' || text[after] == '\t') {
+			after++
+		}
+		lineStart := before == 0 || out[before-1] == '\n' || out[before-1] == '\r'
+		lineEnd := after == len(text) || text[after] == '\n' || text[after] == '\r'
+		closed := rule.ClosedBetweenDigits && !lineStart && !lineEnd &&
+			isDigitByte(out[before-1]) && isDigitByte(text[after])
+
+		switch {
+		case closed:
+			out = append(out[:before], text[i:i+size]...)
+		case lineStart && lineEnd:
+			out = append(out, text[i:after]...)
+		case lineStart:
+			out = append(out, text[i:i+size]...)
+			out = append(out, ' ')
+		case lineEnd:
+			out = append(append(out[:before], ' '), text[i:after]...)
+		default:
+			out = append(append(out[:before], ' '), text[i:i+size]...)
+			out = append(out, ' ')
+		}
+		i = after
+	}
+	return string(out)
+}
+
+// narrowSpaceBeforePunctuation replaces the spacing before marks like the
+// French ; with a single narrow no-break space, adding one when the mark is
+// attached to the end of a word. Marks inside a word, as in 10:30, marks at
+// the start of a line and marks directly after another such mark, as in ?!,
+// are left without one.
+func narrowSpaceBeforePunctuation(text string, table map[rune]PunctuationRule) string {
+	out := make([]byte, 0, len(text)+len(text)/16)
+	for i, r := range text {
+		if !table[r].NarrowSpaceBefore {
+			out = utf8.AppendRune(out, r)
+			continue
+		}
+
+		// Find the horizontal spacing before the mark
+		before := len(out)
+		for before > 0 {
+			prev, size := utf8.DecodeLastRune(out[:before])
+			if !isHorizontalSpace(prev) {
+				break
+			}
+			before -= size
+		}
+		prev, _ := utf8.DecodeLastRune(out[:before])
+		next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+		switch {
+		case before == 0 || prev == '\n' || prev == '\r':
+		case table[prev].NarrowSpaceBefore:
+			out = out[:before]
+		case before == len(out) && next != utf8.RuneError && !isHorizontalSpace(next) &&
+			next != '\n' && next != '\r' && !IsPunctuation(next) && !IsQuote(next):
+		default:
+			out = utf8.AppendRune(out[:before], NarrowNoBreakSpace)
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return string(out)
+}
+
+// removeSpaceAfterPunctuation drops the spacing after marks like the Spanish
+// ¿ so they attach to the next word on the same line
+func removeSpaceAfterPunctuation(text string, table map[rune]PunctuationRule) string {
+	var result strings.Builder
+	result.Grow(len(text))
+	attach := false
+	for _, r := range text {
+		if attach && isHorizontalSpace(r) {
+			continue
+		}
+		attach = table[r].AttachNext
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// isHorizontalSpace reports whether r is a space or tab, including the
+// no-break spaces used by some locales
+func isHorizontalSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\u00A0' || r == NarrowNoBreakSpace
+}
+
+// trimBrackets removes spaces and tabs just inside (…) and […] on one line.
+// Brackets whose trimmed content would read as a marker are left alone, so
+// a second run does not apply a marker the first run ignored.
+func trimBrackets(text string) string {
+	return bracketRegex.ReplaceAllStringFunc(text, func(match string) string {
+		content := strings.Trim(match[1:len(match)-1], " \t")
+		if match[0] == '(' && looksLikeMarker(content) {
+			return match
+		}
+		return match[:1] + content + match[len(match)-1:]
+	})
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isDigitRune(r rune) bool {
+	return r >= '0' && r <= '9'
+}
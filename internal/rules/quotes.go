@@ -2,17 +2,34 @@ package rules
 
 import (
 	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// quoteRegex matches content inside single quotes
+var quoteRegex = regexp.MustCompile(`'\s*([^']*?)\s*'`)
+
 // CleanQuotes removes spaces inside single quotes
 func CleanQuotes(text string) string {
-	// Match content inside single quotes and remove internal spaces
-	quoteRegex := regexp.MustCompile(`'\s*([^']*?)\s*'`)
-	
 	return quoteRegex.ReplaceAllStringFunc(text, func(match string) string {
 		// Extract content between quotes
 		content := match[1 : len(match)-1] // Remove outer quotes
 		// Trim spaces and return with quotes
-		return "'" + regexp.MustCompile(`^\s+|\s+$`).ReplaceAllString(content, "") + "'"
+		return "'" + strings.Trim(content, " \t\n\f\r") + "'"
 	})
-}
\ No newline at end of file
+}
+
+// CleanQuotesWithOptions removes spaces inside single quotes and sets the
+// spacing inside the quote marks of opts.Locale
+func CleanQuotesWithOptions(text string, opts Options) string {
+	return cleanLocaleQuotes(CleanQuotes(text), opts.Locale)
+}
+
+// IsApostrophe reports whether the single quote at offset i in text is an
+// apostrophe, between two letters as in "don't", rather than a quote mark
+func IsApostrophe(text string, i int) bool {
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	next, _ := utf8.DecodeRuneInString(text[i+1:])
+	return unicode.IsLetter(prev) && unicode.IsLetter(next)
+}
@@ -0,0 +1,41 @@
+package rules
+
+import (
+	"fmt"
+)
+
+// Scope limits how far back numbered markers like (up, 5) can reach
+type Scope int
+
+const (
+	// ScopeUnbounded lets markers reach any earlier word in the text
+	ScopeUnbounded Scope = iota
+	// ScopeLine stops markers at the start of their line
+	ScopeLine
+	// ScopeSentence stops markers at the end of the previous sentence
+	ScopeSentence
+	// ScopeParagraph stops markers at the previous blank line
+	ScopeParagraph
+)
+
+var scopeNames = map[Scope]string{
+	ScopeUnbounded: "unbounded",
+	ScopeLine:      "line",
+	ScopeSentence:  "sentence",
+	ScopeParagraph: "paragraph",
+}
+
+// String returns the name of the scope
+func (s Scope) String() string {
+	return scopeNames[s]
+}
+
+// ParseScope returns the scope with the given name
+func ParseScope(name string) (Scope, error) {
+	for scope, scopeName := range scopeNames {
+		if scopeName == name {
+			return scope, nil
+		}
+	}
+	return ScopeUnbounded, fmt.Errorf("invalid scope %q. Use one of [unbounded|line|sentence|paragraph]", name)
+}
@@ -0,0 +1,97 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Whitespace is a policy for the whitespace in processed text
+type Whitespace int
+
+const (
+	// WhitespacePreserve keeps the original whitespace exactly
+	WhitespacePreserve Whitespace = iota
+	// WhitespaceCollapse turns runs of spaces and tabs into a single space
+	// and drops indentation and trailing spaces, keeping line breaks
+	WhitespaceCollapse
+	// WhitespaceLF converts CRLF and lone CR line endings to LF
+	WhitespaceLF
+	// WhitespaceCRLF converts all line endings to CRLF
+	WhitespaceCRLF
+)
+
+var whitespaceNames = map[Whitespace]string{
+	WhitespacePreserve: "preserve",
+	WhitespaceCollapse: "collapse",
+	WhitespaceLF:       "lf",
+	WhitespaceCRLF:     "crlf",
+}
+
+// String returns the name of the policy
+func (w Whitespace) String() string {
+	return whitespaceNames[w]
+}
+
+// ParseWhitespace returns the whitespace policy with the given name
+func ParseWhitespace(name string) (Whitespace, error) {
+	for policy, policyName := range whitespaceNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return WhitespacePreserve, fmt.Errorf("invalid whitespace policy %q. Use one of [preserve|collapse|lf|crlf]", name)
+}
+
+// NormalizeWhitespace applies the whitespace policy to text
+func NormalizeWhitespace(text string, policy Whitespace) string {
+	switch policy {
+	case WhitespaceCollapse:
+		return collapseWhitespace(text)
+	case WhitespaceLF:
+		return toLF(text)
+	case WhitespaceCRLF:
+		return strings.ReplaceAll(toLF(text), "\n", "\r\n")
+	}
+	return text
+}
+
+// toLF converts CRLF and lone CR line endings to LF
+func toLF(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// collapseWhitespace replaces each whitespace run with a single space, or
+// with just its line breaks if it contains any
+func collapseWhitespace(text string) string {
+	var result strings.Builder
+	result.Grow(len(text))
+	i := 0
+	for i < len(text) {
+		if !isSpaceByte(text[i]) {
+			result.WriteByte(text[i])
+			i++
+			continue
+		}
+		end := i
+		newlines := 0
+		for end < len(text) && isSpaceByte(text[end]) {
+			if text[end] == '\n' {
+				newlines++
+			}
+			end++
+		}
+		switch {
+		case newlines > 0:
+			lineEnding := "\n"
+			if strings.Contains(text[i:end], "\r\n") {
+				lineEnding = "\r\n"
+			}
+			result.WriteString(strings.Repeat(lineEnding, newlines))
+		case i > 0 && end < len(text):
+			result.WriteByte(' ')
+		}
+		i = end
+	}
+	return result.String()
+}
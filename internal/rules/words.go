@@ -0,0 +1,257 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WordBuffer accumulates text as alternating runs of whitespace and words,
+// so markers can rewrite the last n words without touching the spacing.
+// Words are whitespace-separated, as with strings.Fields, except that
+// markers written as text, such as unknown markers or markers that cannot
+// apply, are kept apart and are not words.
+type WordBuffer struct {
+	parts []string
+	words []int // indexes into parts of the non-whitespace runs
+	// afterMarker is one more than the index into parts of the last marker,
+	// or of the last word a marker applied to, so text written after it is
+	// not joined to it
+	afterMarker int
+
+	// Index into words of the first word of the current line, sentence
+	// and paragraph
+	lineStart      int
+	sentenceStart  int
+	paragraphStart int
+}
+
+// Write appends text, joining it to the last run when the classes match
+func (b *WordBuffer) Write(text string) {
+	for len(text) > 0 {
+		start, end := nextMarker(text)
+		b.writeRuns(text[:start])
+		if start == len(text) {
+			return
+		}
+		b.parts = append(b.parts, text[start:end])
+		b.afterMarker = len(b.parts)
+		text = text[end:]
+	}
+}
+
+// nextMarker returns the span of the first marker in text, parenthesised
+// text ParseMarker takes as a marker, or len(text) twice if there is none.
+// As in the tokenizer, a "(" is closed by the first ")" after it.
+func nextMarker(text string) (int, int) {
+	open := -1
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			open = i
+		case ')':
+			if open >= 0 {
+				if _, err := ParseMarker(text[open+1 : i]); err != ErrNotMarker {
+					return open, i + 1
+				}
+			}
+			open = -1
+		}
+	}
+	return len(text), len(text)
+}
+
+// writeRuns appends text holding no markers
+func (b *WordBuffer) writeRuns(text string) {
+	for len(text) > 0 {
+		space := isSpaceByte(text[0])
+		end := 1
+		for end < len(text) && isSpaceByte(text[end]) == space {
+			end++
+		}
+		run := text[:end]
+		text = text[end:]
+
+		last := len(b.parts) - 1
+		if last >= 0 && last+1 != b.afterMarker && isSpaceByte(b.parts[last][0]) == space {
+			b.parts[last] += run
+		} else {
+			if !space {
+				b.startWord()
+			}
+			b.parts = append(b.parts, run)
+		}
+		if space {
+			b.endSpace(b.parts[len(b.parts)-1])
+		}
+	}
+}
+
+// startWord records a new word, checking whether the previous one ended a
+// sentence
+func (b *WordBuffer) startWord() {
+	if n := len(b.words); n > 0 && endsSentence(b.parts[b.words[n-1]]) {
+		b.sentenceStart = n
+	}
+	b.words = append(b.words, len(b.parts))
+}
+
+// endSpace updates the line and paragraph starts after a whitespace run.
+// Two newlines in one run mean there is a blank line between them.
+func (b *WordBuffer) endSpace(space string) {
+	switch strings.Count(space, "\n") {
+	case 0:
+		return
+	case 1:
+		b.lineStart = len(b.words)
+	default:
+		b.lineStart = len(b.words)
+		b.sentenceStart = len(b.words)
+		b.paragraphStart = len(b.words)
+	}
+}
+
+// endsSentence reports whether word ends with sentence punctuation, possibly
+// followed by closing quotes or brackets
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, `'")]`)
+	return word != "" && strings.IndexByte(".!?", word[len(word)-1]) >= 0
+}
+
+// WordCount returns the number of words written so far
+func (b *WordBuffer) WordCount() int {
+	return len(b.words)
+}
+
+// WordsInScope returns the number of words written so far in the current
+// line, sentence or paragraph
+func (b *WordBuffer) WordsInScope(scope Scope) int {
+	switch scope {
+	case ScopeLine:
+		return len(b.words) - b.lineStart
+	case ScopeSentence:
+		return len(b.words) - b.sentenceStart
+	case ScopeParagraph:
+		return len(b.words) - b.paragraphStart
+	}
+	return len(b.words)
+}
+
+// ClampToScope limits the count of a numbered marker to the words in scope.
+// It returns the count to apply and, if it had to clamp, a warning message.
+// A count of zero means the marker should be left in place.
+func (b *WordBuffer) ClampToScope(marker string, n int, scope Scope) (int, string) {
+	available := b.WordsInScope(scope)
+	if n <= available {
+		return n, ""
+	}
+	unit := scope.String()
+	if scope == ScopeUnbounded {
+		unit = "text"
+	}
+	if available == 0 {
+		return 0, fmt.Sprintf("%s has no words before it in its %s; left in place", marker, unit)
+	}
+	return available, fmt.Sprintf("%s reaches past the start of its %s; applied to %d words", marker, unit, available)
+}
+
+// boundaryBefore reports whether the word at index i starts a new scope. As
+// in endSpace, the newlines are counted in each whitespace run between it
+// and the word before.
+func (b *WordBuffer) boundaryBefore(i int, scope Scope) bool {
+	newlines := 0
+	for part := b.words[i-1] + 1; part < b.words[i]; part++ {
+		if isSpaceByte(b.parts[part][0]) {
+			newlines = max(newlines, strings.Count(b.parts[part], "\n"))
+		}
+	}
+	switch scope {
+	case ScopeLine:
+		return newlines >= 1
+	case ScopeSentence:
+		return newlines >= 2 || endsSentence(b.parts[b.words[i-1]])
+	case ScopeParagraph:
+		return newlines >= 2
+	}
+	return false
+}
+
+// EndWord ends the last word written, so text written next starts a new
+// word even with no whitespace before it, as after a marker
+func (b *WordBuffer) EndWord() {
+	if last := len(b.parts) - 1; last >= 0 && !isSpaceByte(b.parts[last][0]) {
+		b.afterMarker = len(b.parts)
+	}
+}
+
+// EndsWithWord reports whether the last text before any whitespace at the
+// end of the buffer is a word, not a marker
+func (b *WordBuffer) EndsWithWord() bool {
+	last := len(b.words) - 1
+	if last < 0 {
+		return false
+	}
+	end := len(b.parts) - 1
+	if isSpaceByte(b.parts[end][0]) {
+		end--
+	}
+	return b.words[last] == end
+}
+
+// TrailingSpace returns the whitespace at the end of the buffer
+func (b *WordBuffer) TrailingSpace() string {
+	last := len(b.parts) - 1
+	if last >= 0 && isSpaceByte(b.parts[last][0]) {
+		return b.parts[last]
+	}
+	return ""
+}
+
+// DropSpace drops the whitespace on one side of a marker removed from the
+// end of the buffer, given the whitespace after it in the text: the
+// whitespace after it unless that holds more line breaks than the
+// whitespace before it, which is dropped instead, so markers on a line of
+// their own do not join the lines around them. It reports true if the
+// caller should skip the whitespace after the marker.
+func (b *WordBuffer) DropSpace(after string) bool {
+	if !dropsSpaceAfter(b.TrailingSpace(), after) {
+		b.TrimTrailingSpace()
+		return false
+	}
+	return true
+}
+
+// TrimTrailingSpace drops whitespace at the end of the buffer
+func (b *WordBuffer) TrimTrailingSpace() {
+	last := len(b.parts) - 1
+	if last >= 0 && isSpaceByte(b.parts[last][0]) {
+		b.parts = b.parts[:last]
+	}
+}
+
+// TransformLast applies fn to the last n words, or to all words if fewer
+func (b *WordBuffer) TransformLast(n int, fn func(string) string) {
+	start := len(b.words) - n
+	if start < 0 {
+		start = 0
+	}
+	for _, idx := range b.words[start:] {
+		b.parts[idx] = fn(b.parts[idx])
+	}
+}
+
+// TransformFrom applies fn to n words from the word at index start, or to
+// the words written so far if fewer
+func (b *WordBuffer) TransformFrom(start, n int, fn func(string) string) {
+	end := start + n
+	if end > len(b.words) {
+		end = len(b.words)
+	}
+	for _, idx := range b.words[start:end] {
+		b.parts[idx] = fn(b.parts[idx])
+	}
+}
+
+// String returns the buffered text
+func (b *WordBuffer) String() string {
+	return strings.Join(b.parts, "")
+}
@@ -1,6 +1,7 @@
 package tokenizer
 
 import (
+	"go-reloaded/internal/rules"
 	"strings"
 )
 
@@ -38,14 +39,14 @@ func (t *Tokenizer) Tokenize(text string) []Token {
 	
 	for _, char := range text {
 		switch {
-		case char == ' ' || char == '\t' || char == '\n':
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
 			if current.Len() > 0 {
 				tokens = append(tokens, Token{Type: Word, Value: current.String()})
 				current.Reset()
 			}
 			tokens = append(tokens, Token{Type: Whitespace, Value: string(char)})
 			
-		case char == '\'' || char == '"':
+		case rules.IsQuote(char):
 			if current.Len() > 0 {
 				tokens = append(tokens, Token{Type: Word, Value: current.String()})
 				current.Reset()
@@ -59,7 +60,7 @@ func (t *Tokenizer) Tokenize(text string) []Token {
 			}
 			tokens = append(tokens, Token{Type: Marker, Value: string(char)})
 			
-		case char == ',' || char == '.' || char == '!' || char == '?' || char == ':' || char == ';':
+		case rules.IsPunctuation(char):
 			if current.Len() > 0 {
 				tokens = append(tokens, Token{Type: Word, Value: current.String()})
 				current.Reset()
@@ -78,90 +79,76 @@ func (t *Tokenizer) Tokenize(text string) []Token {
 	return tokens
 }
 
-// Reconstruct rebuilds text from tokens with proper spacing
+// Reconstruct rebuilds text from tokens. Whitespace is kept exactly, so
+// Reconstruct(Tokenize(text)) returns text unchanged.
 func (t *Tokenizer) Reconstruct(tokens []Token) string {
 	var result strings.Builder
 	
-	for i, token := range tokens {
-		switch token.Type {
-		case Word:
-			result.WriteString(token.Value)
-			
-		case Punctuation:
-			result.WriteString(token.Value)
-			
-		case Quote:
-			result.WriteString(token.Value)
-			
-		case Marker:
-			result.WriteString(token.Value)
-			
-		case Whitespace:
-			// Only add whitespace if it's not redundant
-			if i > 0 && i < len(tokens)-1 {
-				prev := tokens[i-1]
-				next := tokens[i+1]
-				if prev.Type != Punctuation && next.Type != Punctuation {
-					result.WriteString(token.Value)
-				}
-			}
-		}
+	for _, token := range tokens {
+		result.WriteString(token.Value)
 	}
 	
 	return result.String()
 }
 
-// PreprocessTokens applies smart preprocessing based on token analysis
+// PreprocessTokens prepares tokens for the pipeline rules. Whitespace is
+// passed through unchanged so the rules see the original layout and any
+// whitespace policy applies to it; spacing around punctuation and quotes is
+// left to the rules themselves.
 func (t *Tokenizer) PreprocessTokens(tokens []Token) string {
-	var result strings.Builder
-	inQuote := false
-	
-	for i, token := range tokens {
-		switch token.Type {
-		case Word:
-			result.WriteString(token.Value)
-			
-		case Punctuation:
-			// Smart punctuation spacing
-			if i > 0 && tokens[i-1].Type == Word {
-				// No space before punctuation
-				result.WriteString(token.Value)
-			} else {
-				result.WriteString(token.Value)
-			}
-			
-		case Quote:
-			// Smart quote handling
-			if !inQuote {
-				// Opening quote - remove space after
-				result.WriteString(token.Value)
-				inQuote = true
-			} else {
-				// Closing quote - remove space before
-				result.WriteString(token.Value)
-				inQuote = false
-			}
-			
-		case Marker:
-			result.WriteString(token.Value)
-			
-		case Whitespace:
-			// Smart whitespace handling
-			if i > 0 && i < len(tokens)-1 {
-				prev := tokens[i-1]
-				next := tokens[i+1]
-				
-				// Add space between words, but handle special cases
-				if prev.Type == Word && next.Type == Word {
-					result.WriteString(" ")
-				} else if prev.Type == Word && next.Type == Marker {
-					result.WriteString(" ")
-				} else if prev.Type == Marker && next.Type == Word {
-					result.WriteString(" ")
-				}
+	return t.Reconstruct(tokens)
+}
+
+// MarkerSpan is a parenthesised piece of text, which may be a marker
+type MarkerSpan struct {
+	// Start and End are the byte offsets of the "(" and just past the ")"
+	Start, End int
+	// Content is the text between the parentheses
+	Content string
+}
+
+// FindMarkers returns the innermost parenthesised spans in tokens, which
+// must be the result of Tokenize
+func (t *Tokenizer) FindMarkers(tokens []Token) []MarkerSpan {
+	var spans []MarkerSpan
+	offset, open := 0, -1
+	for _, token := range tokens {
+		if token.Type == Marker {
+			if token.Value == "(" {
+				open = offset
+			} else if open >= 0 {
+				spans = append(spans, MarkerSpan{Start: open, End: offset + 1})
+				open = -1
 			}
 		}
+		offset += len(token.Value)
 	}
-	
-	return result.String()
-}
\ No newline at end of file
+
+	if len(spans) > 0 {
+		text := t.Reconstruct(tokens)
+		for i := range spans {
+			spans[i].Content = text[spans[i].Start+1 : spans[i].End-1]
+		}
+	}
+	return spans
+}
+
+// ExpandMarkers replaces the markers in tokens that are defined in
+// definitions by what they stand for, and returns the tokens of the result
+func (t *Tokenizer) ExpandMarkers(tokens []Token, definitions rules.MarkerDefinitions) []Token {
+	text := t.Reconstruct(tokens)
+	var result strings.Builder
+	last := 0
+	for _, span := range t.FindMarkers(tokens) {
+		if expanded, ok := definitions.Expand(span.Content); ok {
+			result.WriteString(text[last:span.Start])
+			result.WriteString(expanded)
+			last = span.End
+		}
+	}
+	if last == 0 {
+		return tokens
+	}
+	result.WriteString(text[last:])
+	return t.Tokenize(result.String())
+}
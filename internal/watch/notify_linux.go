@@ -0,0 +1,152 @@
+//go:build linux
+
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// watchMask selects the inotify events that can change what a file holds
+// or whether it exists
+const watchMask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotify watches every directory of a tree with Linux inotify
+type inotify struct {
+	root   string
+	fd     int
+	file   *os.File
+	skip   func(string) bool
+	events chan string
+	errors chan error
+	done   chan struct{}
+	// paths maps each watch descriptor to its directory. Only the reading
+	// goroutine uses it once watching starts.
+	paths map[int32]string
+}
+
+func newNotifier(root string, skip func(string) bool) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &inotify{
+		root: root,
+		fd:   fd,
+		// A non-blocking file is read through the runtime poller, so
+		// Close interrupts a pending Read. Calling its Fd method would
+		// make it blocking again.
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		skip:   skip,
+		events: make(chan string),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+		paths:  make(map[int32]string),
+	}
+	if err := n.addTree(root); err != nil {
+		n.file.Close()
+		return nil, err
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) Events() <-chan string { return n.events }
+func (n *inotify) Errors() <-chan error  { return n.errors }
+
+func (n *inotify) Close() error {
+	close(n.done)
+	return n.file.Close()
+}
+
+// addTree watches dir and the directories under it
+func (n *inotify) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if n.skip(path) {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, path, watchMask)
+		if err != nil {
+			return fmt.Errorf("watching %s: %w", path, err)
+		}
+		n.paths[int32(wd)] = path
+		return nil
+	})
+}
+
+// read turns inotify events into changed paths until the notifier is closed
+func (n *inotify) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if errors.Is(err, os.ErrClosed) {
+			return
+		}
+		if err != nil {
+			n.send(nil, err)
+			continue
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= size; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[start:start+int(event.Len)]), "\x00")
+			offset = start + int(event.Len)
+			n.handle(event, name)
+		}
+	}
+}
+
+// handle reports the path an event is about
+func (n *inotify) handle(event *syscall.InotifyEvent, name string) {
+	dir, ok := n.paths[event.Wd]
+	if event.Mask&syscall.IN_IGNORED != 0 {
+		delete(n.paths, event.Wd)
+	}
+
+	switch {
+	case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+		// Events were lost, so everything may have changed
+		n.send(nil, errors.New("too many changes at once; reprocessing everything"))
+		n.send(&n.root, nil)
+	case !ok || name == "":
+		return
+	default:
+		path := filepath.Join(dir, name)
+		if n.skip(path) {
+			return
+		}
+		if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+			// Files can be added to a new directory before it is watched;
+			// reporting the directory covers them
+			if err := n.addTree(path); err != nil {
+				n.send(nil, err)
+			}
+		}
+		n.send(&path, nil)
+	}
+}
+
+// send delivers a path or an error unless the notifier is closed
+func (n *inotify) send(path *string, err error) {
+	if path != nil {
+		select {
+		case n.events <- *path:
+		case <-n.done:
+		}
+		return
+	}
+	select {
+	case n.errors <- err:
+	case <-n.done:
+	}
+}
@@ -0,0 +1,114 @@
+//go:build !linux
+
+package watch
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often the tree is scanned where inotify is missing
+const pollInterval = 500 * time.Millisecond
+
+// poller finds changes by scanning the tree for new, changed and removed
+// files. It stands in for inotify on other platforms.
+type poller struct {
+	root   string
+	skip   func(string) bool
+	events chan string
+	errors chan error
+	done   chan struct{}
+}
+
+// fileState is what a scan compares to find changed files
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func newNotifier(root string, skip func(string) bool) (notifier, error) {
+	p := &poller{root: root, skip: skip, events: make(chan string), errors: make(chan error), done: make(chan struct{})}
+	files, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	go p.poll(files)
+	return p, nil
+}
+
+func (p *poller) Events() <-chan string { return p.events }
+func (p *poller) Errors() <-chan error  { return p.errors }
+
+func (p *poller) Close() error {
+	close(p.done)
+	return nil
+}
+
+// poll scans the tree until the poller is closed, reporting the files that
+// differ from the last scan
+func (p *poller) poll(files map[string]fileState) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		current, err := p.scan()
+		if err != nil {
+			select {
+			case p.errors <- err:
+			case <-p.done:
+				return
+			}
+			continue
+		}
+		var changed []string
+		for path, state := range current {
+			if old, ok := files[path]; !ok || old != state {
+				changed = append(changed, path)
+			}
+		}
+		for path := range files {
+			if _, ok := current[path]; !ok {
+				changed = append(changed, path)
+			}
+		}
+		files = current
+		for _, path := range changed {
+			select {
+			case p.events <- path:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// scan records the size and modification time of every file in the tree
+func (p *poller) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p.skip(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,224 @@
+// Package watch keeps a directory of processed files up to date with a
+// directory of sources, reprocessing each source file when it changes.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultDebounce is how long a file must go unchanged before it is
+// reprocessed, so a burst of writes from an editor is processed once
+const DefaultDebounce = 100 * time.Millisecond
+
+// Config configures Run
+type Config struct {
+	// Src is the directory of source files and Out the directory the
+	// processed files are written to, at the same relative paths
+	Src, Out string
+	// Process returns the processed text of the source file at path
+	Process func(path, text string) (string, error)
+	// Debounce is how long a file must go unchanged before it is
+	// reprocessed. Zero means DefaultDebounce.
+	Debounce time.Duration
+	// Log receives a line for every file processed or removed and for
+	// every error. Nil discards them.
+	Log *log.Logger
+}
+
+// notifier reports changes under a directory tree. A changed path may be a
+// file or a directory, which stands for everything in it.
+type notifier interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+// Run processes every file in cfg.Src into cfg.Out, then watches cfg.Src
+// and reprocesses the files that change until ctx is done. Output files
+// whose source is removed are removed too. Hidden files and directories,
+// editor backups ending in "~", and cfg.Out if it is inside cfg.Src are
+// left alone. Errors after the start are logged and watching goes on.
+func Run(ctx context.Context, cfg Config) error {
+	w, err := newWatcher(cfg)
+	if err != nil {
+		return err
+	}
+	n, err := newNotifier(w.src, w.skip)
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+	w.update(w.src)
+
+	timers := make(map[string]*time.Timer)
+	ready := make(chan string)
+	for {
+		select {
+		case <-ctx.Done():
+			for _, t := range timers {
+				t.Stop()
+			}
+			return nil
+		case path := <-n.Events():
+			// Restart the wait for the path on every change
+			if t, ok := timers[path]; ok {
+				t.Stop()
+			}
+			timers[path] = time.AfterFunc(w.debounce, func() {
+				select {
+				case ready <- path:
+				case <-ctx.Done():
+				}
+			})
+		case path := <-ready:
+			delete(timers, path)
+			w.update(path)
+		case err := <-n.Errors():
+			w.log.Printf("error: %v", err)
+		}
+	}
+}
+
+// watcher holds the settings of a Run
+type watcher struct {
+	src, out string
+	process  func(path, text string) (string, error)
+	debounce time.Duration
+	log      *log.Logger
+}
+
+func newWatcher(cfg Config) (*watcher, error) {
+	w := &watcher{process: cfg.Process, debounce: cfg.Debounce, log: cfg.Log}
+	if w.debounce <= 0 {
+		w.debounce = DefaultDebounce
+	}
+	if w.log == nil {
+		w.log = log.New(io.Discard, "", 0)
+	}
+	var err error
+	if w.src, err = filepath.Abs(cfg.Src); err != nil {
+		return nil, err
+	}
+	if w.out, err = filepath.Abs(cfg.Out); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(w.src); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, errors.New(cfg.Src + " is not a directory")
+	}
+	if w.src == w.out {
+		return nil, errors.New("the output directory must differ from the source directory")
+	}
+	return w, nil
+}
+
+// skip reports whether path under the source directory is left alone
+func (w *watcher) skip(path string) bool {
+	name := filepath.Base(path)
+	return path != w.src && (strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")) || path == w.out
+}
+
+// update brings the output for path up to date: a file is processed, a
+// directory has all its files processed, and the output of a path that no
+// longer exists is removed
+func (w *watcher) update(path string) {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		w.remove(path)
+	case err != nil:
+		w.log.Printf("error: %v", err)
+	case info.IsDir():
+		filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				w.log.Printf("error: %v", err)
+			case w.skip(p) && d.IsDir():
+				return filepath.SkipDir
+			case !w.skip(p) && d.Type().IsRegular():
+				w.processFile(p)
+			}
+			return nil
+		})
+	case info.Mode().IsRegular() && !w.skip(path):
+		w.processFile(path)
+	}
+}
+
+// processFile writes the processed text of the source file at path to the
+// output directory, replacing the old output in one step
+func (w *watcher) processFile(path string) {
+	rel, out := w.output(path)
+	text, err := os.ReadFile(path)
+	if err == nil {
+		var result string
+		if result, err = w.process(path, string(text)); err == nil {
+			err = writeFile(out, result)
+		}
+	}
+	if err != nil {
+		w.log.Printf("error: %s: %v", rel, err)
+		return
+	}
+	w.log.Printf("processed %s", rel)
+}
+
+// remove removes the output of a source file or directory that is gone
+func (w *watcher) remove(path string) {
+	if path == w.src {
+		w.log.Printf("error: %s was removed", w.src)
+		return
+	}
+	rel, out := w.output(path)
+	if _, err := os.Lstat(out); errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err := os.RemoveAll(out); err != nil {
+		w.log.Printf("error: %v", err)
+		return
+	}
+	w.log.Printf("removed %s", rel)
+}
+
+// output returns the path of a source file relative to the source
+// directory, and the path of its output
+func (w *watcher) output(path string) (string, string) {
+	rel, err := filepath.Rel(w.src, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return rel, filepath.Join(w.out, rel)
+}
+
+// writeFile writes text to a temporary file next to path and renames it
+// over path, so readers never see half a file
+func writeFile(path, text string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
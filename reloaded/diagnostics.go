@@ -0,0 +1,82 @@
+package reloaded
+
+import (
+	"fmt"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+)
+
+// Severity tells how serious a diagnostic is
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText encodes the severity by name, as in JSON
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic describes a problem found while processing. Line and Column
+// are 1-based and point into the input text; Column counts runes.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// String formats the diagnostic as "line:column: severity: message"
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Explanation is the output of a text with the steps that produced it
+type Explanation struct {
+	Output      string       `json:"output"`
+	Steps       []Step       `json:"steps"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Step is a rule that changed the text, with the whole text before and
+// after it
+type Step struct {
+	Rule   string `json:"rule"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// diagnostics converts the diagnostics of the processors
+func diagnostics(found []rules.Diagnostic) []Diagnostic {
+	if found == nil {
+		return nil
+	}
+	converted := make([]Diagnostic, len(found))
+	for i, d := range found {
+		severity := Warning
+		if d.Severity == rules.Error {
+			severity = Error
+		}
+		converted[i] = Diagnostic{Line: d.Line, Column: d.Column, Severity: severity, Message: d.Message}
+	}
+	return converted
+}
+
+// explanation converts the explanation of a processor
+func explanation(e processor.Explanation) Explanation {
+	var steps []Step
+	for _, step := range e.Steps {
+		steps = append(steps, Step{Rule: step.Rule, Before: step.Before, After: step.After})
+	}
+	return Explanation{Output: e.Output, Steps: steps, Diagnostics: diagnostics(e.Diagnostics)}
+}
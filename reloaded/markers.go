@@ -0,0 +1,117 @@
+package reloaded
+
+import (
+	"errors"
+	"fmt"
+	"go-reloaded/internal/rules"
+)
+
+// MarkerInfo describes a marker in the registry
+type MarkerInfo struct {
+	Name        string
+	Description string
+	// TakesCount is set for markers with a numbered form like (up, 3)
+	TakesCount bool
+}
+
+// Markers lists the known markers in the order they are documented
+func Markers() []MarkerInfo {
+	markers := make([]MarkerInfo, len(rules.MarkerNames))
+	for i, name := range rules.MarkerNames {
+		markers[i] = MarkerInfo{Name: name, Description: rules.MarkerDescriptions[name], TakesCount: rules.TakesCount(name)}
+	}
+	return markers
+}
+
+// Marker is a parsed marker like (up), (cap, 3), (low>, 2) or (up:begin)
+type Marker struct {
+	Name string
+	// Count is the number of words the marker applies to
+	Count     int
+	Numbered  bool
+	Direction Direction
+}
+
+// String returns the marker as it is written
+func (m Marker) String() string {
+	name := m.Name + directionSuffixes[m.Direction]
+	if m.Numbered {
+		return fmt.Sprintf("(%s, %d)", name, m.Count)
+	}
+	return "(" + name + ")"
+}
+
+// Direction says which words a marker applies to
+type Direction int
+
+const (
+	// Backward markers like (up, 2) apply to the words before them
+	Backward Direction = iota
+	// Forward markers like (up>, 2) apply to the words after them
+	Forward
+	// RangeBegin and RangeEnd markers like (up:begin) and (up:end) apply to
+	// the words between them
+	RangeBegin
+	RangeEnd
+)
+
+// directionSuffixes is what follows the marker name for each direction
+var directionSuffixes = map[Direction]string{
+	Forward:    ">",
+	RangeBegin: ":begin",
+	RangeEnd:   ":end",
+}
+
+// directions maps the directions the processors use to these
+var directions = map[rules.Direction]Direction{
+	rules.Backward:   Backward,
+	rules.Forward:    Forward,
+	rules.RangeBegin: RangeBegin,
+	rules.RangeEnd:   RangeEnd,
+}
+
+// MarkerError describes parenthesised text meant as a marker that is
+// malformed or has an unknown name, with a suggested fix if there is one
+type MarkerError struct {
+	// Content is the text between the parentheses
+	Content string
+	// Unknown is set when the name is not a marker name
+	Unknown bool
+	// Suggestion is the marker the content was probably meant to be, or ""
+	Suggestion string
+	Reason     string
+}
+
+func (e *MarkerError) Error() string {
+	kind := "malformed"
+	if e.Unknown {
+		kind = "unknown"
+	}
+	message := fmt.Sprintf("%s marker (%s)", kind, e.Content)
+	if e.Reason != "" {
+		message += ": " + e.Reason
+	}
+	if e.Suggestion != "" {
+		message += "; did you mean " + e.Suggestion + "?"
+	}
+	return message
+}
+
+// ErrNotMarker is returned by ParseMarker for parenthesised text that is
+// not meant as a marker, like "(see below)"
+var ErrNotMarker = errors.New("not a marker")
+
+// ParseMarker parses the text between a pair of parentheses. It returns
+// ErrNotMarker for ordinary text and a *MarkerError for text that looks
+// like a marker but is not a valid one.
+func ParseMarker(content string) (Marker, error) {
+	marker, err := rules.ParseMarker(content)
+	var markerErr *rules.MarkerError
+	switch {
+	case errors.As(err, &markerErr):
+		return Marker{}, &MarkerError{Content: markerErr.Content, Unknown: markerErr.Unknown, Suggestion: markerErr.Suggestion, Reason: markerErr.Reason}
+	case err != nil:
+		return Marker{}, ErrNotMarker
+	}
+	return Marker{Name: marker.Name, Count: marker.Count, Numbered: marker.Numbered, Direction: directions[marker.Direction]}, nil
+}
@@ -0,0 +1,213 @@
+// Package reloaded is the public API of go-reloaded. It formats text by
+// applying markers like (up, 2) and (hex), fixing punctuation, quotes and
+// articles, and can explain which rules changed a text.
+//
+//	proc, err := reloaded.New(reloaded.Pipeline, reloaded.WithScope(reloaded.ScopeLine))
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(proc.Process("it was a apple (up, 2) , ok")) // it was AN APPLE, ok
+//
+// The package follows semantic versioning: within a major version, exported
+// names keep their meaning and the output of a mode only changes to fix
+// bugs. Everything under internal/ may change at any time, so the types
+// here are defined by this package rather than taken from there.
+package reloaded
+
+import (
+	"fmt"
+	"go-reloaded/internal/processor"
+)
+
+// Version is the version of the API
+const Version = "1.0.0"
+
+// Mode selects a processor implementation. All modes apply the same rules;
+// they differ in how they find markers.
+type Mode string
+
+const (
+	// Pipeline applies each rule to the whole text in turn
+	Pipeline Mode = "pipeline"
+	// FSM applies markers with a finite state machine
+	FSM Mode = "fsm"
+	// Hybrid tokenizes the text before applying the pipeline rules
+	Hybrid Mode = "hybrid"
+)
+
+// Modes lists the modes in the order they are documented
+func Modes() []Mode {
+	modes := make([]Mode, len(processor.ModeNames))
+	for i, name := range processor.ModeNames {
+		modes[i] = Mode(name)
+	}
+	return modes
+}
+
+// Processor formats text. Processors keep no state between calls and are
+// safe for concurrent use.
+type Processor interface {
+	// Process returns text with all rules applied. It is idempotent.
+	Process(text string) string
+	// ProcessWithDiagnostics also reports problems found, such as marker
+	// counts clamped to their scope
+	ProcessWithDiagnostics(text string) (string, []Diagnostic)
+	// Explain also reports the rules that changed the text
+	Explain(text string) Explanation
+}
+
+// New creates a processor for mode
+func New(mode Mode, opts ...Option) (Processor, error) {
+	converted, err := options(opts)
+	if err != nil {
+		return nil, err
+	}
+	proc, err := processor.New(string(mode), converted...)
+	if err != nil {
+		return nil, err
+	}
+	return explainProcessor{proc}, nil
+}
+
+// NewParallel creates a processor that splits text on blank lines and
+// processes the paragraphs concurrently with the given number of workers,
+// or one per CPU if workers is zero or less. Numbered markers only see
+// words in their own paragraph.
+func NewParallel(mode Mode, workers int, opts ...Option) (Processor, error) {
+	proc, err := New(mode, opts...)
+	if err != nil {
+		return nil, err
+	}
+	converted, _ := options(opts)
+	parallel := processor.NewParallel(func() processor.Processor {
+		proc, _ := processor.New(string(mode), converted...)
+		return proc
+	}, workers)
+	return parallelProcessor{parallel: parallel, whole: proc}, nil
+}
+
+// explainProcessor gives a processor of the processor package the types of
+// this package
+type explainProcessor struct {
+	proc processor.ExplainProcessor
+}
+
+func (p explainProcessor) Process(text string) string {
+	return p.proc.Process(text)
+}
+
+func (p explainProcessor) ProcessWithDiagnostics(text string) (string, []Diagnostic) {
+	output, found := p.proc.ProcessWithDiagnostics(text)
+	return output, diagnostics(found)
+}
+
+func (p explainProcessor) Explain(text string) Explanation {
+	return explanation(p.proc.Explain(text))
+}
+
+// parallelProcessor processes paragraphs concurrently, and explains texts
+// with whole, a processor of the same mode
+type parallelProcessor struct {
+	parallel *processor.Parallel
+	whole    Processor
+}
+
+func (p parallelProcessor) Process(text string) string {
+	return p.parallel.Process(text)
+}
+
+func (p parallelProcessor) ProcessWithDiagnostics(text string) (string, []Diagnostic) {
+	output, found := p.parallel.ProcessWithDiagnostics(text)
+	return output, diagnostics(found)
+}
+
+// Explain processes the text in one piece, without splitting it into
+// paragraphs, because the steps of each rule are over the whole text. Its
+// output is that of New with the same mode and options.
+func (p parallelProcessor) Explain(text string) Explanation {
+	return p.whole.Explain(text)
+}
+
+// Option configures a processor
+type Option struct {
+	opt processor.Option
+	// err is set when the option was given a value it does not know
+	err error
+}
+
+// options returns the options of the processor package that opts stand
+// for, or the error of the first invalid one
+func options(opts []Option) ([]processor.Option, error) {
+	converted := make([]processor.Option, 0, len(opts))
+	for _, opt := range opts {
+		if opt.err != nil {
+			return nil, opt.err
+		}
+		if opt.opt != nil {
+			converted = append(converted, opt.opt)
+		}
+	}
+	return converted, nil
+}
+
+// WithScope limits how far back numbered markers like (up, 5) can reach.
+// New reports values that are not one of the Scope constants.
+func WithScope(scope Scope) Option {
+	converted, ok := scopes[scope]
+	if !ok {
+		return Option{err: fmt.Errorf("invalid scope %d. Use one of the Scope constants", scope)}
+	}
+	return Option{opt: processor.WithScope(converted)}
+}
+
+// WithWhitespace sets the whitespace policy applied to the processed text.
+// New reports values that are not one of the Whitespace constants.
+func WithWhitespace(policy Whitespace) Option {
+	converted, ok := whitespaces[policy]
+	if !ok {
+		return Option{err: fmt.Errorf("invalid whitespace policy %d. Use one of the Whitespace constants", policy)}
+	}
+	return Option{opt: processor.WithWhitespace(converted)}
+}
+
+// WithLocale sets the punctuation and quote conventions of the processed
+// text. New reports values that are not one of the Locale constants.
+func WithLocale(locale Locale) Option {
+	converted, ok := locales[locale]
+	if !ok {
+		return Option{err: fmt.Errorf("invalid locale %d. Use one of the Locale constants", locale)}
+	}
+	return Option{opt: processor.WithLocale(converted)}
+}
+
+// WithRules applies only the named rules, in the given order. New reports
+// unknown or repeated rules, and articles listed before case.
+func WithRules(names ...string) Option {
+	return Option{opt: processor.WithRules(names)}
+}
+
+// WithMarkers adds user-defined markers, given as a map from each name to
+// another marker name, as in "upper": "up", or to text with markers in it,
+// as in "shout": "(up) !". New reports unknown markers and definitions that
+// lead back to themselves.
+func WithMarkers(definitions map[string]string) Option {
+	return Option{opt: processor.WithMarkers(definitions)}
+}
+
+// WithMarkerPolicy sets what happens to unknown and malformed markers like
+// (upp) or (cap, -2), and to markers that cannot apply where they are: they
+// are kept, stripped, or kept and reported as errors. They are reported as
+// diagnostics under every policy. New reports values that are not one of
+// the MarkerPolicy constants.
+func WithMarkerPolicy(policy MarkerPolicy) Option {
+	converted, ok := markerPolicies[policy]
+	if !ok {
+		return Option{err: fmt.Errorf("invalid marker policy %d. Use one of the MarkerPolicy constants", policy)}
+	}
+	return Option{opt: processor.WithMarkerPolicy(converted)}
+}
+
+// RuleNames lists the rules in the order they are applied by default
+func RuleNames() []string {
+	return append([]string{}, processor.RuleNames...)
+}
@@ -0,0 +1,181 @@
+package reloaded
+
+import (
+	"fmt"
+	"go-reloaded/internal/rules"
+)
+
+// Scope limits how far back numbered markers can reach
+type Scope int
+
+const (
+	// ScopeUnbounded lets markers reach any earlier word in the text
+	ScopeUnbounded Scope = iota
+	// ScopeLine stops markers at the start of their line
+	ScopeLine
+	// ScopeSentence stops markers at the end of the previous sentence
+	ScopeSentence
+	// ScopeParagraph stops markers at the previous blank line
+	ScopeParagraph
+)
+
+var scopeNames = map[Scope]string{
+	ScopeUnbounded: "unbounded",
+	ScopeLine:      "line",
+	ScopeSentence:  "sentence",
+	ScopeParagraph: "paragraph",
+}
+
+// scopes maps each scope to the one the processors use
+var scopes = map[Scope]rules.Scope{
+	ScopeUnbounded: rules.ScopeUnbounded,
+	ScopeLine:      rules.ScopeLine,
+	ScopeSentence:  rules.ScopeSentence,
+	ScopeParagraph: rules.ScopeParagraph,
+}
+
+// String returns the name of the scope
+func (s Scope) String() string {
+	return scopeNames[s]
+}
+
+// ParseScope returns the scope with the given name
+func ParseScope(name string) (Scope, error) {
+	for scope, scopeName := range scopeNames {
+		if scopeName == name {
+			return scope, nil
+		}
+	}
+	return ScopeUnbounded, fmt.Errorf("invalid scope %q. Use one of [unbounded|line|sentence|paragraph]", name)
+}
+
+// Whitespace is a policy for whitespace in the processed text
+type Whitespace int
+
+const (
+	// WhitespacePreserve keeps the original whitespace exactly
+	WhitespacePreserve Whitespace = iota
+	// WhitespaceCollapse turns runs of spaces and tabs into a single space
+	// and drops indentation and trailing spaces, keeping line breaks
+	WhitespaceCollapse
+	// WhitespaceLF converts CRLF and lone CR line endings to LF
+	WhitespaceLF
+	// WhitespaceCRLF converts all line endings to CRLF
+	WhitespaceCRLF
+)
+
+var whitespaceNames = map[Whitespace]string{
+	WhitespacePreserve: "preserve",
+	WhitespaceCollapse: "collapse",
+	WhitespaceLF:       "lf",
+	WhitespaceCRLF:     "crlf",
+}
+
+// whitespaces maps each whitespace policy to the one the processors use
+var whitespaces = map[Whitespace]rules.Whitespace{
+	WhitespacePreserve: rules.WhitespacePreserve,
+	WhitespaceCollapse: rules.WhitespaceCollapse,
+	WhitespaceLF:       rules.WhitespaceLF,
+	WhitespaceCRLF:     rules.WhitespaceCRLF,
+}
+
+// String returns the name of the policy
+func (w Whitespace) String() string {
+	return whitespaceNames[w]
+}
+
+// ParseWhitespace returns the whitespace policy with the given name
+func ParseWhitespace(name string) (Whitespace, error) {
+	for policy, policyName := range whitespaceNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return WhitespacePreserve, fmt.Errorf("invalid whitespace policy %q. Use one of [preserve|collapse|lf|crlf]", name)
+}
+
+// MarkerPolicy says what happens to unknown, malformed and misplaced markers
+type MarkerPolicy int
+
+const (
+	// MarkersKeep leaves them in the text and warns about them
+	MarkersKeep MarkerPolicy = iota
+	// MarkersStrip removes them from the text and warns about them
+	MarkersStrip
+	// MarkersError leaves them in the text and reports them as errors
+	MarkersError
+)
+
+var markerPolicyNames = map[MarkerPolicy]string{
+	MarkersKeep:  "keep",
+	MarkersStrip: "strip",
+	MarkersError: "error",
+}
+
+// markerPolicies maps each marker policy to the one the processors use
+var markerPolicies = map[MarkerPolicy]rules.MarkerPolicy{
+	MarkersKeep:  rules.MarkersKeep,
+	MarkersStrip: rules.MarkersStrip,
+	MarkersError: rules.MarkersError,
+}
+
+// String returns the name of the policy
+func (p MarkerPolicy) String() string {
+	return markerPolicyNames[p]
+}
+
+// ParseMarkerPolicy returns the marker policy with the given name
+func ParseMarkerPolicy(name string) (MarkerPolicy, error) {
+	for policy, policyName := range markerPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return MarkersKeep, fmt.Errorf("invalid marker policy %q. Use one of [keep|strip|error]", name)
+}
+
+// Locale selects punctuation and quote conventions
+type Locale int
+
+const (
+	// LocaleEnglish attaches all punctuation to the word before it
+	LocaleEnglish Locale = iota
+	// LocaleFrench puts a narrow no-break space before ;:!? and inside
+	// « guillemets »
+	LocaleFrench
+	// LocaleGerman cleans spaces inside „quotes“ and »guillemets«
+	LocaleGerman
+	// LocaleSpanish attaches ¿ and ¡ to the word after them and cleans
+	// spaces inside «guillemets»
+	LocaleSpanish
+)
+
+var localeNames = map[Locale]string{
+	LocaleEnglish: "en",
+	LocaleFrench:  "fr",
+	LocaleGerman:  "de",
+	LocaleSpanish: "es",
+}
+
+// locales maps each locale to the one the processors use
+var locales = map[Locale]rules.Locale{
+	LocaleEnglish: rules.LocaleEnglish,
+	LocaleFrench:  rules.LocaleFrench,
+	LocaleGerman:  rules.LocaleGerman,
+	LocaleSpanish: rules.LocaleSpanish,
+}
+
+// String returns the name of the locale
+func (l Locale) String() string {
+	return localeNames[l]
+}
+
+// ParseLocale returns the locale with the given name
+func ParseLocale(name string) (Locale, error) {
+	for locale, localeName := range localeNames {
+		if localeName == name {
+			return locale, nil
+		}
+	}
+	return LocaleEnglish, fmt.Errorf("invalid locale %q. Use one of [en|fr|de|es]", name)
+}
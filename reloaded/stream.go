@@ -0,0 +1,95 @@
+package reloaded
+
+import "go-reloaded/internal/processor"
+
+// Stream processes text as it is typed or received, giving the same final
+// output as the Pipeline mode on the whole text. Feed characters with
+// ProcessChar, edit anywhere with ApplyEdit, show Preview while typing and
+// call Flush at the end of the text.
+//
+// A Stream is one editing session: its methods are safe to call from
+// several goroutines, but each text needs its own Stream.
+type Stream struct {
+	fsm *processor.RealtimeFSM
+}
+
+// NewStream creates a stream. It reports the options New would reject for
+// their values.
+func NewStream(opts ...Option) (*Stream, error) {
+	converted, err := options(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{fsm: processor.NewRealtimeFSM(converted...)}, nil
+}
+
+// ProcessChar appends a character to the text, moves the cursor to the end
+// and returns any output that became final
+func (s *Stream) ProcessChar(char rune) string {
+	return s.fsm.ProcessChar(char)
+}
+
+// Flush makes all of the text final, as at its end, and returns the output
+// that became final. Characters added afterwards start a new text.
+func (s *Stream) Flush() string {
+	return s.fsm.Flush()
+}
+
+// ApplyEdit replaces delete characters of the text at offset with insert.
+// Offsets count runes.
+func (s *Stream) ApplyEdit(offset, delete int, insert string) error {
+	return s.fsm.ApplyEdit(offset, delete, insert)
+}
+
+// InsertChar inserts a character at the cursor and moves the cursor past it
+func (s *Stream) InsertChar(char rune) {
+	s.fsm.InsertChar(char)
+}
+
+// DeleteChar deletes the character before the cursor, like backspace. It
+// reports false if the cursor is at the start of the text.
+func (s *Stream) DeleteChar() bool {
+	return s.fsm.DeleteChar()
+}
+
+// MoveCursor moves the cursor by delta characters, staying within the text
+func (s *Stream) MoveCursor(delta int) {
+	s.fsm.MoveCursor(delta)
+}
+
+// SetCursor moves the cursor to pos, staying within the text
+func (s *Stream) SetCursor(pos int) {
+	s.fsm.SetCursor(pos)
+}
+
+// Cursor returns the cursor position in characters
+func (s *Stream) Cursor() int {
+	return s.fsm.Cursor()
+}
+
+// Text returns the text fed to the stream
+func (s *Stream) Text() string {
+	return s.fsm.Text()
+}
+
+// Output returns the output that is final so far
+func (s *Stream) Output() string {
+	return s.fsm.Output()
+}
+
+// Preview returns the final output followed by the processed text before
+// the word, quote or marker still being typed. Unlike Output, the end of it
+// can still change.
+func (s *Stream) Preview() string {
+	return s.fsm.Preview()
+}
+
+// GetCurrentBuffer returns the word, quote or marker still being typed
+func (s *Stream) GetCurrentBuffer() string {
+	return s.fsm.GetCurrentBuffer()
+}
+
+// Reset clears the text and the output
+func (s *Stream) Reset() {
+	s.fsm.Reset()
+}
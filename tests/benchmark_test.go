@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"strings"
+	"testing"
+)
+
+const benchmarkParagraph = `A friend sent me 1E (hex) messages yesterday , and I replied 10 (bin) times ! 
+He said ' thanks ' but then shouted HELLO (low) . 
+It was A honest mistake , I guess . 
+This is truly amazing (up, 2) experience (cap) . 
+We talked about a orange , a apple , and a umbrella — all while laughing (cap, 4) . 
+It was a peaceful moment ... but also a reminder of how amazing (up, 3) everything (cap) can be .
+
+`
+
+// benchmarkInput repeats the sample paragraph until it reaches size bytes
+func benchmarkInput(size int) string {
+	return strings.Repeat(benchmarkParagraph, size/len(benchmarkParagraph)+1)
+}
+
+// benchmarkLongLine builds a single line of size bytes with no newlines
+func benchmarkLongLine(size int) string {
+	line := strings.ReplaceAll(benchmarkParagraph, "\n", "")
+	return strings.Repeat(line, size/len(line)+1)
+}
+
+func benchmarkProcessor(b *testing.B, proc processor.Processor, input string) {
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		proc.Process(input)
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	benchmarkProcessor(b, processor.NewPipeline(), benchmarkInput(1<<20))
+}
+
+func BenchmarkFSM(b *testing.B) {
+	benchmarkProcessor(b, processor.NewFSM(), benchmarkInput(1<<20))
+}
+
+func BenchmarkHybrid(b *testing.B) {
+	benchmarkProcessor(b, processor.NewHybrid(), benchmarkInput(1<<20))
+}
+
+func BenchmarkPipelineLongLine(b *testing.B) {
+	benchmarkProcessor(b, processor.NewPipeline(), benchmarkLongLine(1<<20))
+}
+
+func BenchmarkFSMLongLine(b *testing.B) {
+	benchmarkProcessor(b, processor.NewFSM(), benchmarkLongLine(1<<20))
+}
+
+func BenchmarkHybridLongLine(b *testing.B) {
+	benchmarkProcessor(b, processor.NewHybrid(), benchmarkLongLine(1<<20))
+}
+
+func BenchmarkPipelineParallel(b *testing.B) {
+	benchmarkProcessor(b, processor.NewParallel(func() processor.Processor { return processor.NewPipeline() }, 0), benchmarkInput(1<<20))
+}
+
+func BenchmarkFSMParallel(b *testing.B) {
+	benchmarkProcessor(b, processor.NewParallel(func() processor.Processor { return processor.NewFSM() }, 0), benchmarkInput(1<<20))
+}
+
+func BenchmarkUnknownMarkersLongLine(b *testing.B) {
+	proc := processor.NewPipeline(processor.WithMarkerPolicy(rules.MarkersStrip))
+	benchmarkProcessor(b, proc, strings.Repeat("x (upp) ", (1<<20)/8))
+}
+
+func BenchmarkUnpairedRangesLongLine(b *testing.B) {
+	benchmarkProcessor(b, processor.NewFSM(), strings.Repeat("(up:begin) a ", (1<<20)/13))
+}
+
+// Each forward marker waits for more words than the line has
+func BenchmarkForwardMarkersLongLine(b *testing.B) {
+	for _, mode := range processor.ModeNames {
+		b.Run(mode, func(b *testing.B) {
+			proc, _ := processor.New(mode)
+			benchmarkProcessor(b, proc, strings.Repeat("(up>, 100000) a ", (1<<16)/16))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"sync"
+	"testing"
+)
+
+// Run with -race: these tests share one processor between goroutines and
+// check every result against sequential processing
+
+func TestProcessorsConcurrentUse(t *testing.T) {
+	shared := map[string]processor.Processor{
+		"pipeline": processor.NewPipeline(),
+		"fsm":      processor.NewFSM(),
+		"hybrid":   processor.NewHybrid(),
+		"parallel": processor.NewParallel(func() processor.Processor { return processor.NewFSM() }, 2),
+	}
+	const goroutines = 8
+
+	for name, proc := range shared {
+		t.Run(name, func(t *testing.T) {
+			expected := make([]string, len(realtimeParityInputs))
+			for i, input := range realtimeParityInputs {
+				expected[i] = proc.Process(input)
+			}
+
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for n := range realtimeParityInputs {
+						// Start each goroutine at a different input so
+						// different texts are processed at the same time
+						i := (n + g) % len(realtimeParityInputs)
+						if got := proc.Process(realtimeParityInputs[i]); got != expected[i] {
+							t.Errorf("Input %q: expected %q, got %q", realtimeParityInputs[i], expected[i], got)
+						}
+						if dp, ok := proc.(processor.DiagnosticProcessor); ok {
+							if got, _ := dp.ProcessWithDiagnostics(realtimeParityInputs[i]); got != expected[i] {
+								t.Errorf("Input %q with diagnostics: expected %q, got %q", realtimeParityInputs[i], expected[i], got)
+							}
+						}
+						if ep, ok := proc.(processor.ExplainProcessor); ok {
+							if got := ep.Explain(realtimeParityInputs[i]).Output; got != expected[i] {
+								t.Errorf("Input %q explained: expected %q, got %q", realtimeParityInputs[i], expected[i], got)
+							}
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestRealtimeFSMConcurrentPreview(t *testing.T) {
+	input := "it was a apple (up, 2) , said 'the' owl (cap) 1E (hex)\n\nthen (low) FIN"
+	fsm := processor.NewRealtimeFSM()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				fsm.Preview()
+				fsm.Output()
+				fsm.GetCurrentBuffer()
+				fsm.Text()
+				fsm.Cursor()
+			}
+		}()
+	}
+
+	for _, char := range input {
+		fsm.InsertChar(char)
+	}
+	fsm.MoveCursor(-3)
+	fsm.DeleteChar()
+	fsm.InsertChar('I')
+	close(done)
+	wg.Wait()
+
+	fsm.Flush()
+	edited := []rune(input)
+	edited[len(edited)-4] = 'I'
+	if expected := processor.NewPipeline().Process(string(edited)); fsm.Output() != expected {
+		t.Errorf("Expected %q, got %q", expected, fsm.Output())
+	}
+}
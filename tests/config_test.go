@@ -0,0 +1,321 @@
+package tests
+
+import (
+	"errors"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const yamlConfig = `# project settings
+mode: fsm
+scope: paragraph
+rules: [case, numbers, quotes, punctuation, articles]  # no whitespace
+articles:
+  a: [university, "one"]
+  an:
+    - herb
+markers:
+  upper: up
+overrides:
+  - files: "docs/fr/**"
+    locale: fr
+    markers:
+      majuscule: up
+  - files: '*.md'
+    whitespace: collapse
+`
+
+const tomlConfig = `# project settings
+mode = "fsm"
+scope = 'paragraph'
+rules = [
+  "case", "numbers", "quotes",
+  "punctuation", "articles",  # no whitespace
+]
+
+[articles]
+a = ["university", "one"]
+an = ["herb"]
+
+[markers]
+upper = "up"
+
+[[overrides]]
+files = "docs/fr/**"
+locale = "fr"
+markers.majuscule = "up"
+
+[[overrides]]
+files = "*.md"
+whitespace = "collapse"
+`
+
+func TestConfigFormats(t *testing.T) {
+	expected := &config.Config{
+		Settings: config.Settings{
+			Mode:     "fsm",
+			Scope:    "paragraph",
+			Rules:    []string{"case", "numbers", "quotes", "punctuation", "articles"},
+			Articles: rules.ArticleExceptions{A: []string{"university", "one"}, An: []string{"herb"}},
+			Markers:  map[string]string{"upper": "up"},
+		},
+		Overrides: []config.Override{
+			{Files: "docs/fr/**", Settings: config.Settings{Locale: "fr", Markers: map[string]string{"majuscule": "up"}}},
+			{Files: "*.md", Settings: config.Settings{Whitespace: "collapse"}},
+		},
+	}
+
+	for name, data := range map[string]string{".reloaded.yaml": yamlConfig, ".reloaded.toml": tomlConfig} {
+		cfg, err := config.Parse(name, []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		expected.Path = name
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("%s: expected\n%+v\ngot\n%+v", name, expected, cfg)
+		}
+	}
+}
+
+func TestConfigProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"bad.toml", `mode = "fast"
+rules = ["case", "spelling",
+  "case"]
+[articles]
+the = ["x"]
+[markers]
+up = "low"
+loud = "shout"
+[[overrides]]
+locale = "fr"
+[[overrides]]
+files = "[a"
+scope = "page"
+colour = 1
+`, `bad.toml:1: invalid mode "fast". Use one of [pipeline|fsm|hybrid]
+bad.toml:2: unknown rule "spelling". Use some of [case|numbers|quotes|punctuation|articles|whitespace]
+bad.toml:2: rule "case" is listed twice
+bad.toml:5: unknown key "the" in articles. Use a or an
+bad.toml:7: marker (up) would replace the built-in marker
+bad.toml:8: marker (loud) uses unknown marker (shout). Use one of [hex|bin|up|low|cap] or a defined marker
+bad.toml:9: override has no files glob
+bad.toml:12: invalid files glob "[a"
+bad.toml:13: invalid scope "page". Use one of [unbounded|line|sentence|paragraph]
+bad.toml:14: unknown key "colour"`},
+		{"bad.yaml", "mode: fsm\nlocale:\n  - fr\narticles:\n  a: [two words]\noverrides:\n  - files: x\n    overrides: []\n", `bad.yaml:3: locale must be a single value
+bad.yaml:5: article exception "two words" must be a single word
+bad.yaml:8: overrides cannot be nested`},
+		{"indent.yaml", "mode: fsm\n  scope: line\n", "indent.yaml:2: unexpected indentation"},
+		{"dup.yaml", "mode: fsm\nmode: hybrid\n", `dup.yaml:2: duplicate key "mode"`},
+		{"tabs.yaml", "articles:\n\ta: [x]\n", "tabs.yaml:2: indent with spaces, not tabs"},
+		{"flow.yaml", "markers: {upper: up}\n", "flow.yaml:1: {...} mappings are not supported; write one key per line"},
+		{"table.toml", "[markers]\nupper = \"up\"\n[markers]\n", "table.toml:3: table [markers] is defined twice"},
+		{"string.toml", "mode = \"fsm\n", `string.toml:1: invalid string "fsm`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Parse(tt.name, []byte(tt.data))
+			var configErr *config.Error
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected a config error, got %v", err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestConfigDiscoveryAndOverrides(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "docs", "fr", "guide")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".reloaded.yaml"), []byte(yamlConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := config.Find(nested)
+	if err != nil || path != filepath.Join(root, ".reloaded.yaml") {
+		t.Fatalf("Expected to find the config in %s, got %q, %v", root, path, err)
+	}
+	cfg, err := config.Discover(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file       string
+		locale     string
+		whitespace string
+		markers    int
+	}{
+		{"notes.txt", "", "", 1},
+		{"docs/fr/guide/intro.txt", "fr", "", 2},
+		{"docs/fr/guide/intro.md", "fr", "collapse", 2},
+		{"docs/readme.md", "", "collapse", 1},
+		{"../outside/docs/fr/a.txt", "", "", 1},
+	}
+	for _, tt := range tests {
+		settings := cfg.For(filepath.Join(root, filepath.FromSlash(tt.file)))
+		if settings.Locale != tt.locale || settings.Whitespace != tt.whitespace || len(settings.Markers) != tt.markers || settings.Mode != "fsm" {
+			t.Errorf("%s: expected locale %q, whitespace %q and %d markers, got %+v", tt.file, tt.locale, tt.whitespace, tt.markers, settings)
+		}
+	}
+
+	if cfg, err := config.Discover(filepath.Dir(root)); err != nil || cfg != nil {
+		// Only possible if a config file sits above the temp directory
+		if _, statErr := os.Stat(filepath.Join(filepath.Dir(root), ".reloaded.yaml")); statErr != nil {
+			t.Errorf("Expected no config above %s, got %v, %v", root, cfg, err)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide.md", true},
+		{"*.md", "docs/guide.txt", false},
+		{"docs/*.md", "docs/guide.md", true},
+		{"docs/*.md", "docs/fr/guide.md", false},
+		{"docs/**", "docs/fr/guide.md", true},
+		{"docs/**/*.md", "docs/guide.md", true},
+		{"docs/**/*.md", "docs/a/b/guide.md", true},
+		{"docs/**/*.md", "src/guide.md", false},
+		{"/docs/*.txt", "docs/a.txt", true},
+	}
+	for _, tt := range tests {
+		if got := config.MatchGlob(tt.pattern, tt.name); got != tt.match {
+			t.Errorf("MatchGlob(%q, %q): expected %v, got %v", tt.pattern, tt.name, tt.match, got)
+		}
+	}
+}
+
+func TestArticleExceptions(t *testing.T) {
+	opts := rules.Options{Articles: rules.ArticleExceptions{A: []string{"university", "One"}, An: []string{"herb", "MBA"}}}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"an university and a one-off", "a university and a one-off"},
+		{"A herb and a herbal tea", "An herb and an herbal tea"},
+		{"a mba and an hour", "an mba and an hour"},
+		{"a apple and an banana", "an apple and a banana"},
+	}
+	for _, tt := range tests {
+		if got := rules.FixArticlesWithOptions(tt.input, opts); got != tt.expected {
+			t.Errorf("FixArticlesWithOptions(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+		if again := rules.FixArticlesWithOptions(tt.expected, opts); again != tt.expected {
+			t.Errorf("FixArticlesWithOptions is not idempotent on %q: got %q", tt.expected, again)
+		}
+	}
+}
+
+func TestMarkerAliasesAndRuleSelection(t *testing.T) {
+	aliases := processor.WithMarkers(map[string]string{"upper": "up", "title": "cap"})
+	tests := []struct {
+		name     string
+		opts     []processor.Option
+		input    string
+		expected string
+	}{
+		{"aliases", []processor.Option{aliases}, "so loud (upper, 2) and quiet (title) (uppers)", "SO LOUD and Quiet (uppers)"},
+		{"markers only", []processor.Option{processor.WithRules([]string{"case", "numbers"})}, "a apple (up) , 1E (hex)", "a APPLE , 30"},
+		{"no rules", []processor.Option{processor.WithRules([]string{})}, "a apple (up) ,", "a apple (up) ,"},
+		{"numbers before case", []processor.Option{processor.WithRules([]string{"numbers", "case", "articles"})}, "a apple (up) 1E (hex)", "an APPLE 30"},
+	}
+	for _, tt := range tests {
+		for _, mode := range processor.ModeNames {
+			proc, _ := processor.New(mode, tt.opts...)
+			if got := proc.Process(tt.input); got != tt.expected {
+				t.Errorf("%s in %s mode: expected %q, got %q", tt.name, mode, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestCLIConfig(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	project := filepath.Join(dir, "project")
+	if err := os.MkdirAll(filepath.Join(project, "docs", "fr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".reloaded.yaml"), []byte(yamlConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := "a herb (upper) and a university , a apple ? end (majuscule)\n"
+	for _, name := range []string{"in.txt", "docs/fr/in.txt"} {
+		if err := os.WriteFile(filepath.Join(project, name), []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"config mode", []string{"in.txt", "out.txt"}, "an HERB and a university, an apple? end (majuscule)\n"},
+		{"override", []string{"docs/fr/in.txt", "out.txt"}, "an HERB and a university, an apple\u202f? END\n"},
+		{"flag beats config", []string{"--locale", "en", "docs/fr/in.txt", "out.txt"}, "an HERB and a university, an apple? END\n"},
+		{"explicit config", []string{"--config", filepath.Join(project, "docs", ".none.toml"), "in.txt", "out.txt", "pipeline"}, ""},
+	}
+	if err := os.WriteFile(filepath.Join(project, "docs", ".none.toml"), []byte("# defaults only\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests[3].expected = processor.NewPipeline().Process(input)
+
+	for _, tt := range tests {
+		cmd := exec.Command(binary, tt.args...)
+		cmd.Dir = project
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: CLI failed: %v\nOutput: %s", tt.name, err, output)
+		}
+		content, err := os.ReadFile(filepath.Join(project, "out.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, string(content))
+		}
+	}
+
+	validate := exec.Command(binary, "config", "validate")
+	validate.Dir = filepath.Join(project, "docs", "fr")
+	if output, err := validate.CombinedOutput(); err != nil || !strings.HasSuffix(string(output), ".reloaded.yaml: ok\n") {
+		t.Errorf("Expected config validate to pass, got %v: %s", err, output)
+	}
+	if err := os.WriteFile(filepath.Join(project, "bad.yaml"), []byte("mode: fast\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	validate = exec.Command(binary, "config", "validate", "bad.yaml")
+	validate.Dir = project
+	output, err := validate.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || string(output) != "bad.yaml:1: invalid mode \"fast\". Use one of [pipeline|fsm|hybrid]\n" {
+		t.Errorf("Expected config validate to fail with exit 1, got %v: %s", err, output)
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-reloaded/internal/daemon"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDaemonRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{"process", `{"jsonrpc":"2.0","id":1,"method":"process","params":{"text":"so (up) , a owl"}}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"output":"SO, an owl","diagnostics":[]}}`},
+		{"process with options", `{"jsonrpc":"2.0","id":"a","method":"process","params":{"text":"a\nb c (up, 3)","mode":"fsm","scope":"line"}}`,
+			`{"jsonrpc":"2.0","id":"a","result":{"output":"a\nB C","diagnostics":[{"line":2,"column":5,"severity":"warning","message":"(up, 3) reaches past the start of its line; applied to 2 words"}]}}`},
+		{"process with rules and markers", `{"jsonrpc":"2.0","id":"b","method":"process","params":{"text":"so (upp) it (shout) 1E (hex)","rules":["case"],"unknown-markers":"strip","markers":{"shout":"up"}}}`,
+			`{"jsonrpc":"2.0","id":"b","result":{"output":"so IT 1E (hex)","diagnostics":[{"line":1,"column":4,"severity":"warning","message":"unknown marker (upp); did you mean (up)?"}]}}`},
+		{"explain", `{"jsonrpc":"2.0","id":2,"method":"explain","params":{"text":"1E (hex) files","mode":"hybrid"}}`,
+			`{"jsonrpc":"2.0","id":2,"result":{"output":"30 files","steps":[{"rule":"numbers","before":"1E (hex) files","after":"30 files"}],"diagnostics":[]}}`},
+		{"list markers", `{"jsonrpc":"2.0","id":3,"method":"listMarkers"}`,
+			`{"jsonrpc":"2.0","id":3,"result":[{"name":"hex","description":"converts a hexadecimal number to decimal","takesCount":false},{"name":"bin","description":"converts a binary number to decimal","takesCount":false},{"name":"up","description":"converts to uppercase","takesCount":true},{"name":"low","description":"converts to lowercase","takesCount":true},{"name":"cap","description":"capitalizes","takesCount":true}]}`},
+		{"notification", `{"jsonrpc":"2.0","method":"process","params":{"text":"a"}}`, ``},
+		{"invalid mode", `{"jsonrpc":"2.0","id":4,"method":"process","params":{"mode":"fast"}}`,
+			`{"jsonrpc":"2.0","id":4,"error":{"code":-32602,"message":"invalid mode \"fast\". Use one of [pipeline|fsm|hybrid]"}}`},
+		{"unknown method", `{"jsonrpc":"2.0","id":5,"method":"format"}`,
+			`{"jsonrpc":"2.0","id":5,"error":{"code":-32601,"message":"method \"format\" not found"}}`},
+		{"not json-rpc", `{"id":6,"method":"process"}`,
+			`{"jsonrpc":"2.0","id":6,"error":{"code":-32600,"message":"expected a JSON-RPC 2.0 request with a method"}}`},
+		{"parse error", `{"jsonrpc"`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			rw := struct {
+				io.Reader
+				io.Writer
+			}{strings.NewReader(tt.request + "\n"), &out}
+			if err := daemon.ServeConn(rw); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSuffix(out.String(), "\n"); got != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDaemonConcurrentClients(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	listener, err := daemon.Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm()&0077 != 0 {
+		t.Errorf("Expected a socket only its owner can use, got %v: %v", info.Mode(), err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- daemon.Serve(ctx, listener)
+	}()
+
+	const clients, requests = 8, 50
+	modes := []string{"pipeline", "fsm", "hybrid"}
+	var wg sync.WaitGroup
+	for c := 0; c < clients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			conn, err := net.Dial("unix", socket)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			replies := bufio.NewScanner(conn)
+
+			for i := 0; i < requests; i++ {
+				text := fmt.Sprintf("client %d said hello (up, 2) to a owl %d", c, i)
+				expected := fmt.Sprintf("client %d SAID HELLO to an owl %d", c, i)
+				fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%d,"method":"process","params":{"text":%q,"mode":%q}}`+"\n", i, text, modes[(c+i)%len(modes)])
+				if !replies.Scan() {
+					t.Errorf("Client %d: no reply to request %d: %v", c, i, replies.Err())
+					return
+				}
+				var reply struct {
+					ID     int
+					Result struct{ Output string }
+				}
+				if err := json.Unmarshal(replies.Bytes(), &reply); err != nil {
+					t.Errorf("Client %d: %v", c, err)
+					return
+				}
+				if reply.ID != i || reply.Result.Output != expected {
+					t.Errorf("Client %d request %d: expected %q, got %s", c, i, expected, replies.Text())
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v after shutdown", err)
+	}
+}
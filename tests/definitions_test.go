@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"errors"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/tokenizer"
+	"testing"
+)
+
+var markerDefinitions = map[string]string{
+	"upper": "up",
+	"shout": "(up) !",
+	"name":  "(cap, 2)",
+	"loud":  "(shout)",
+}
+
+func TestExpandMarkerDefinitions(t *testing.T) {
+	definitions := rules.MarkerDefinitions(markerDefinitions)
+	tests := []struct {
+		content  string
+		expected string
+		ok       bool
+	}{
+		{"upper", "(up)", true},
+		{"upper, 3", "(up, 3)", true},
+		{"shout", "(up) !", true},
+		{"name", "(cap, 2)", true},
+		{"name, 5", "(cap, 2)", true},
+		{"loud, 2", "(up, 2) !", true},
+		{"up", "", false},
+		{"shouts", "", false},
+		{" shout", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := definitions.Expand(tt.content)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("Expand(%q): expected %q, %v, got %q, %v", tt.content, tt.expected, tt.ok, got, ok)
+		}
+	}
+
+	tok := tokenizer.NewTokenizer()
+	input := "so (loud) and (see below) (upper, 2)"
+	if got := tok.Reconstruct(tok.ExpandMarkers(tok.Tokenize(input), definitions)); got != "so (up) ! and (see below) (up, 2)" {
+		t.Errorf("ExpandMarkers(%q): got %q", input, got)
+	}
+}
+
+func TestMarkerDefinitionsAcrossModes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"hello (shout) world", "HELLO! world"},
+		{"john smith (name) was here", "John Smith was here"},
+		{"so loud (upper, 2)", "SO LOUD"},
+		{"a wow (loud, 2) ok", "A WOW! ok"},
+		{"keep (shouts) and (see below)", "keep (shouts) and (see below)"},
+	}
+	for _, mode := range processor.ModeNames {
+		proc, err := processor.New(mode, processor.WithMarkers(markerDefinitions))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			got := proc.Process(tt.input)
+			if got != tt.expected {
+				t.Errorf("%s mode, %q: expected %q, got %q", mode, tt.input, tt.expected, got)
+			}
+			if again := proc.Process(got); again != got {
+				t.Errorf("%s mode is not idempotent on %q: got %q", mode, got, again)
+			}
+		}
+	}
+
+	steps := processor.NewPipeline(processor.WithMarkers(markerDefinitions)).Explain("hi (shout)").Steps
+	if len(steps) == 0 || steps[0].Rule != "definitions" || steps[0].After != "hi (up) !" {
+		t.Errorf("Expected a definitions step first, got %+v", steps)
+	}
+}
+
+func TestInvalidMarkerDefinitions(t *testing.T) {
+	tests := []struct {
+		definitions map[string]string
+		expected    string
+	}{
+		{map[string]string{"a": "(b)", "b": "(c) (a)", "c": "up"}, "marker (a) is defined in terms of itself: (a) → (b) → (a)\nmarker (b) is defined in terms of itself: (b) → (a) → (b)"},
+		{map[string]string{"again": "(again, 2)"}, "marker (again) is defined in terms of itself: (again) → (again)"},
+		{map[string]string{"oops": "(nope) !"}, "marker (oops) uses unknown marker (nope). Use one of [hex|bin|up|low|cap] or a defined marker"},
+		{map[string]string{"blank": " "}, "marker (blank) has an empty definition"},
+		{map[string]string{"cap": "up"}, "marker (cap) would replace the built-in marker"},
+		{map[string]string{"two words": "up"}, `marker name "two words" must be a single word of letters`},
+	}
+	for _, tt := range tests {
+		for _, mode := range processor.ModeNames {
+			_, err := processor.New(mode, processor.WithMarkers(tt.definitions))
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("%s mode, %v: expected %q, got %v", mode, tt.definitions, tt.expected, err)
+			}
+		}
+	}
+}
+
+func TestConfigMarkerDefinitions(t *testing.T) {
+	data := `markers:
+  shout: (up) !
+  loop: (again)
+  again: (loop)
+overrides:
+  - files: "*.md"
+    markers:
+      loud: (shout, 2)
+      quiet: (whisper)
+`
+	_, err := config.Parse("markers.yaml", []byte(data))
+	var configErr *config.Error
+	expected := `markers.yaml:3: marker (loop) is defined in terms of itself: (loop) → (again) → (loop)
+markers.yaml:4: marker (again) is defined in terms of itself: (again) → (loop) → (again)
+markers.yaml:9: marker (quiet) uses unknown marker (whisper). Use one of [hex|bin|up|low|cap] or a defined marker`
+	if !errors.As(err, &configErr) || err.Error() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%v", expected, err)
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"go-reloaded/internal/diff"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"one line", "a\nb\nc\n", "a\nB\nc\n", "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"from empty", "", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"to empty", "a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"no newline", "a\nb", "a\nc", "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+		{"newline added", "a", "a\n", "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n"},
+		{"two hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n"},
+		{"joined hunks", "1\n2\n3\n4\n5\n6\n7\n", "one\n2\n3\n4\n5\n6\nseven\n",
+			"@@ -1,7 +1,7 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n-7\n+seven\n"},
+	}
+	for _, tt := range tests {
+		expected := tt.expected
+		if expected != "" {
+			expected = "--- old\n+++ new\n" + expected
+		}
+		if got := diff.Unified("old", "new", tt.old, tt.new); got != expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, expected, got)
+		}
+	}
+}
+
+func TestCLIOutputFormat(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	input := "it was a apple (up) .\nunchanged\nend ,ok"
+	expected := "it was an APPLE.\nunchanged\nend, ok"
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) string {
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("CLI failed: %v\nOutput: %s", err, output)
+		}
+		content, err := os.ReadFile(filepath.Join(dir, args[len(args)-1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	patch := run("--output-format=patch", "in.txt", "out.patch")
+	if want := diff.Unified("in.txt", "in.txt", input, expected); patch != want {
+		t.Errorf("Expected patch\n%s\ngot\n%s", want, patch)
+	}
+	dated := run("--output-format", "diff", "in.txt", "out.diff")
+	lines := strings.SplitN(dated, "\n", 3)
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "--- in.txt\t") || !strings.HasPrefix(lines[1], "+++ in.txt\t") || lines[2] != strings.SplitN(patch, "\n", 3)[2] {
+		t.Errorf("Expected a dated diff of in.txt, got\n%s", dated)
+	}
+
+	if _, err := exec.LookPath("patch"); err != nil {
+		t.Skip("patch is not installed")
+	}
+	for _, name := range []string{"out.patch", "out.diff"} {
+		if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("patch", "-p0", "-i", name)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("patch -p0 failed on %s: %v\n%s", name, err, output)
+		}
+		if content, _ := os.ReadFile(filepath.Join(dir, "in.txt")); string(content) != expected {
+			t.Errorf("%s: expected the patched file to be %q, got %q", name, expected, content)
+		}
+	}
+
+	cmd := exec.Command(binary, "--output-format=html", "in.txt", "out.txt")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err == nil || string(output) != "Error: invalid output format \"html\". Use one of [text|diff|patch]\n" {
+		t.Errorf("Expected an error for an unknown format, got %v: %s", err, output)
+	}
+}
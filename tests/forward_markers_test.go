@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"strings"
+	"testing"
+)
+
+func TestForwardAndRangeMarkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"forward count", "(up>, 3) the quick brown fox", "THE QUICK BROWN fox"},
+		{"forward single", "say (cap>) hello world", "say Hello world"},
+		{"forward on its own line", "say\n(up>)\nhello world", "say\nHELLO world"},
+		{"forward before a line break", "say (up>)\nhello world", "say\nHELLO world"},
+		{"range across punctuation and quotes", "(up:begin) he said , 'hi there' and left . (up:end) ok", "HE SAID, 'HI THERE' AND LEFT. ok"},
+		{"range across apostrophes", "(up:begin) don't stop (up:end) now", "DON'T STOP now"},
+		{"forward after an apostrophe", "it's (up>, 2) a test", "it's A TEST"},
+		{"forward over an apostrophe", "(up>, 2) we'll see it", "WE'LL SEE it"},
+		{"range across lines", "a (cap:begin) long\nstory told (cap:end) end", "a Long\nStory Told end"},
+		{"nested ranges", "(up:begin) a (low:begin) B C (low:end) d (up:end)", "A B C D"},
+		{"same name nested", "(low:begin) A (low:begin) B (low:end) C (low:end) D", "a b c D"},
+		{"forward applies after its last word", "(up>, 2) a (low) b", "A B"},
+		{"backward after forward", "(low>, 2) ONE TWO (up) three", "one TWO three"},
+		{"forward reaching the end", "it is (up>, 9) almost over", "it is ALMOST OVER"},
+		{"unpaired begin", "x (up:begin) never closed", "x (up:begin) never closed"},
+		{"unpaired end", "stray (up:end) end", "stray (up:end) end"},
+		{"forward with nothing after", "hello (up>)", "hello (up>)"},
+		{"forward glued to a word", "x(up>) y", "x(up>) y"},
+		{"range with a count", "(up:begin, 2) a b (up:end)", "(up:begin, 2) a b (up:end)"},
+	}
+	for _, tt := range tests {
+		for _, mode := range processor.ModeNames {
+			proc, _ := processor.New(mode)
+			got := proc.Process(tt.input)
+			if got != tt.expected {
+				t.Errorf("%s mode, %s: expected %q, got %q", mode, tt.name, tt.expected, got)
+			}
+			if again := proc.Process(got); again != got {
+				t.Errorf("%s mode, %s: not idempotent, %q became %q", mode, tt.name, got, again)
+			}
+		}
+	}
+}
+
+func TestKeptMarkersAreNotWords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		stripped string
+	}{
+		{"a (up>, 2) (up, 2) b", "A B", "A B"},
+		{"a (upp) (up)", "A (upp)", "A"},
+		{"(upp) (up) x", "(upp) (up) x", "x"},
+		{"3.14 (hex) (up)", "3.20", "3.20"},
+		{"(low:end) (cap) ' (up, 2)", "(low:end) (cap) '", "'"},
+	}
+	for _, mode := range processor.ModeNames {
+		for _, policy := range []rules.MarkerPolicy{rules.MarkersKeep, rules.MarkersStrip} {
+			proc, _ := processor.New(mode, processor.WithMarkerPolicy(policy))
+			for _, tt := range tests {
+				expected := tt.expected
+				if policy == rules.MarkersStrip {
+					expected = tt.stripped
+				}
+				if got := proc.Process(tt.input); got != expected {
+					t.Errorf("%s mode, %s markers, %q: expected %q, got %q", mode, policy, tt.input, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestForwardMarkerScope(t *testing.T) {
+	tests := []struct {
+		scope       rules.Scope
+		input       string
+		expected    string
+		diagnostics string
+	}{
+		{rules.ScopeUnbounded, "(up>, 5) one two\nthree", "ONE TWO\nTHREE", "1:1: warning: (up>, 5) reaches past the end of its text; applied to 3 words"},
+		{rules.ScopeLine, "(up>, 5) one two\nthree", "ONE TWO\nthree", "1:1: warning: (up>, 5) reaches past the end of its line; applied to 2 words"},
+		{rules.ScopeSentence, "(cap>, 3) one. two three", "One. two three", "1:1: warning: (cap>, 3) reaches past the end of its sentence; applied to 1 words"},
+		{rules.ScopeParagraph, "x (up>, 2)\n\ny z", "x\n\nY Z", ""},
+		{rules.ScopeLine, "(up:begin) one\ntwo (up:end)", "ONE\nTWO", ""},
+	}
+	for _, tt := range tests {
+		for _, mode := range processor.ModeNames {
+			proc, _ := processor.New(mode, processor.WithScope(tt.scope), processor.WithRules([]string{"case"}))
+			got, diagnostics := proc.ProcessWithDiagnostics(tt.input)
+			var messages []string
+			for _, d := range diagnostics {
+				messages = append(messages, d.String())
+			}
+			if got != tt.expected || strings.Join(messages, "\n") != tt.diagnostics {
+				t.Errorf("%s mode, %s scope, %q: expected %q with %q, got %q with %q", mode, tt.scope, tt.input, tt.expected, tt.diagnostics, got, messages)
+			}
+		}
+	}
+}
+
+func TestParseDirectionalMarker(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"up>", "(up>)"},
+		{"cap>, 3", "(cap>, 3)"},
+		{"low:begin", "(low:begin)"},
+		{"low:end", "(low:end)"},
+		{"upp>, 2", "unknown marker (upp>, 2); did you mean (up>, 2)?"},
+		{"up:middle", "malformed marker (up:middle): a range marker ends in :begin or :end"},
+		{"up:begin, 2", "malformed marker (up:begin, 2): it takes no count; did you mean (up:begin)?"},
+		{"hex>", "malformed marker (hex>): only case markers can point forward or mark a range; did you mean (hex)?"},
+		{"note: see above", "not a marker"},
+	}
+	for _, tt := range tests {
+		marker, err := rules.ParseMarker(tt.content)
+		got := marker.String()
+		if errors.Is(err, rules.ErrNotMarker) {
+			got = "not a marker"
+		} else if err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("ParseMarker(%q): expected %q, got %q", tt.content, tt.expected, got)
+		}
+	}
+}
+
+func TestLSPDirectionalMarkers(t *testing.T) {
+	text := "(up>, 2) so exciting\n(cap:begin) a story\nhello (low:end) and (up>)"
+	replies := lspSession(t,
+		lspOpen(text),
+		lspRequest(1, "textDocument/hover", map[string]interface{}{"position": lspPosition(0, 2)}),
+		map[string]interface{}{"method": "exit"},
+	)
+
+	var got []string
+	for _, d := range replies[0]["params"].(map[string]interface{})["diagnostics"].([]interface{}) {
+		d := d.(map[string]interface{})
+		start := d["range"].(map[string]interface{})["start"].(map[string]interface{})
+		got = append(got, fmt.Sprintf("%v:%v %s", start["line"], start["character"], d["message"]))
+	}
+	expected := []string{
+		"1:0 marker (cap:begin) has no matching (cap:end) after it",
+		"2:6 marker (low:end) has no matching (low:begin) before it",
+		"2:20 marker (up>) must be followed by a space and the words it applies to",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected diagnostics:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	hover := lspResult(t, replies, 1).(map[string]interface{})["contents"].(map[string]interface{})["value"]
+	if expected := "**(up>, 2)** converts to uppercase the 2 words after it"; hover != expected {
+		t.Errorf("Expected hover %q, got %q", expected, hover)
+	}
+}
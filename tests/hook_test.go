@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"go-reloaded/internal/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHookConfig(t *testing.T) {
+	cfg, err := config.Parse("/repo/.reloaded.yaml", []byte("hook:\n  files: [\"docs/**\", \"*.md\"]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file   string
+		hooked bool
+	}{
+		{"/repo/docs/a/b.txt", true},
+		{"/repo/README.md", true},
+		{"/repo/notes.txt", false},
+		{"/elsewhere/docs/a.txt", false},
+	}
+	for _, tt := range tests {
+		if got := cfg.Hooked(tt.file); got != tt.hooked {
+			t.Errorf("Hooked(%q): expected %v, got %v", tt.file, tt.hooked, got)
+		}
+	}
+	if cfg, _ := config.Parse("/repo/.reloaded.yaml", []byte("mode: fsm\n")); !cfg.Hooked("/repo/a/notes.txt") || cfg.Hooked("/repo/a.md") {
+		t.Error("Expected the hook to process only .txt files by default")
+	}
+
+	bad := "hook:\n  files: [\"[\"]\n  check: true\noverrides:\n  - files: \"*.md\"\n    hook:\n      files: [\"*\"]\n"
+	_, err = config.Parse("bad.yaml", []byte(bad))
+	expected := "bad.yaml:2: invalid files glob \"[\"\nbad.yaml:3: unknown key \"check\" in hook. Use files\nbad.yaml:7: hook cannot be set in an override"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected\n%s\ngot\n%v", expected, err)
+	}
+}
+
+func TestGitHook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	repo := filepath.Join(dir, "repo")
+	run := func(name string, args ...string) (string, error) {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	must := func(name string, args ...string) string {
+		t.Helper()
+		output, err := run(name, args...)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v\n%s", name, strings.Join(args, " "), err, output)
+		}
+		return output
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(repo, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	if err := os.Mkdir(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	must("git", "init", "-q")
+
+	// A hook that go-reloaded did not write is kept unless forced
+	write(".git/hooks/pre-commit", "#!/bin/sh\nexit 0\n")
+	if output, err := run(binary, "hook", "install"); err == nil || !strings.Contains(output, "use --force") {
+		t.Fatalf("Expected install to refuse to replace a hook, got %v: %s", err, output)
+	}
+	must(binary, "hook", "install", "--force")
+
+	// Fix mode stages the processed content of matching files
+	write(".reloaded.yaml", "hook:\n  files: [\"*.txt\", \"docs/**\"]\n")
+	write("a.txt", "it was a apple (up)\n")
+	write("docs/b.md", "hello , world\n")
+	write("notes.md", "a apple (up)\n")
+	must("git", "add", ".")
+	must("git", "commit", "-q", "-m", "first")
+	for name, expected := range map[string]string{
+		"a.txt":     "it was an APPLE\n",
+		"docs/b.md": "hello, world\n",
+		"notes.md":  "a apple (up)\n",
+	} {
+		if got := must("git", "show", "HEAD:"+name); got != expected {
+			t.Errorf("Committed %s: expected %q, got %q", name, expected, got)
+		}
+		if got := read(name); got != expected {
+			t.Errorf("Working tree %s: expected %q, got %q", name, expected, got)
+		}
+	}
+
+	// Only the staged blob is processed; unstaged edits stay out of the
+	// commit and the working tree is left alone
+	write("a.txt", "so (cap)\n")
+	must("git", "add", "a.txt")
+	write("a.txt", "so (cap)\nnot staged\n")
+	must("git", "commit", "-q", "-m", "second")
+	if got := must("git", "show", "HEAD:a.txt"); got != "So\n" {
+		t.Errorf("Expected the staged content to be processed, got %q", got)
+	}
+	if got := read("a.txt"); got != "so (cap)\nnot staged\n" {
+		t.Errorf("Expected the working tree to be left alone, got %q", got)
+	}
+	must("git", "checkout", "a.txt")
+
+	// Check mode stops the commit without touching anything
+	must(binary, "hook", "install", "--check")
+	write("c.txt", "a apple\n")
+	must("git", "add", "c.txt")
+	output, err := run("git", "commit", "-q", "-m", "third")
+	if err == nil || !strings.Contains(output, "c.txt: needs processing") {
+		t.Fatalf("Expected the commit to fail in check mode, got %v: %s", err, output)
+	}
+	if got := must("git", "show", ":c.txt"); got != "a apple\n" {
+		t.Errorf("Expected the staged content to be kept in check mode, got %q", got)
+	}
+	write("c.txt", "an apple\n")
+	must("git", "add", "c.txt")
+	must("git", "commit", "-q", "-m", "third")
+	if got := must("git", "log", "--format=%s"); got != "third\nsecond\nfirst\n" {
+		t.Errorf("Unexpected history: %q", got)
+	}
+}
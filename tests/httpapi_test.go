@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"go-reloaded/internal/httpapi"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPProcess(t *testing.T) {
+	handler := httpapi.NewHandler(httpapi.Config{MaxBodyBytes: 128})
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"plain text", "POST", "/v1/process", "text/plain", "it was a apple (up, 2) , ok", 200, "it was AN APPLE, ok"},
+		{"plain text with options", "POST", "/v1/process?mode=fsm&locale=fr", "text/plain; charset=utf-8", "Quoi ?", 200, "Quoi ?"},
+		{"json", "POST", "/v1/process", "application/json", `{"text":"1E (hex) files","mode":"hybrid"}`, 200, `{"output":"30 files","diagnostics":[]}`},
+		{"json diagnostics", "POST", "/v1/process", "application/json", `{"text":"a\nb c (up, 3)","scope":"line"}`, 200, `{"output":"a\nB C","diagnostics":[{"line":2,"column":5,"severity":"warning","message":"(up, 3) reaches past the start of its line; applied to 2 words"}]}`},
+		{"json rules and markers", "POST", "/v1/process", "application/json", `{"text":"so (upp) it (shout) 1E (hex)","rules":["case"],"unknown-markers":"strip","markers":{"shout":"up"}}`, 200, `{"output":"so IT 1E (hex)","diagnostics":[{"line":1,"column":4,"severity":"warning","message":"unknown marker (upp); did you mean (up)?"}]}`},
+		{"plain text rules", "POST", "/v1/process?rules=numbers,whitespace&unknown-markers=strip", "text/plain", "so (up) 1E (hex) (upp)", 200, "so (up) 30"},
+		{"invalid rules", "POST", "/v1/process?rules=case,cases", "text/plain", "a", 400, ""},
+		{"invalid marker policy", "POST", "/v1/process", "application/json", `{"text":"a","unknown-markers":"drop"}`, 400, `{"error":"invalid marker policy \"drop\". Use one of [keep|strip|error]"}`},
+		{"invalid mode", "POST", "/v1/process", "application/json", `{"text":"a","mode":"fast"}`, 400, `{"error":"invalid mode \"fast\". Use one of [pipeline|fsm|hybrid]"}`},
+		{"invalid option", "POST", "/v1/process?scope=page", "text/plain", "a", 400, `{"error":"invalid scope \"page\". Use one of [unbounded|line|sentence|paragraph]"}`},
+		{"unknown field", "POST", "/v1/process", "application/json", `{"txt":"a"}`, 400, `{"error":"invalid request body: json: unknown field \"txt\""}`},
+		{"too large", "POST", "/v1/process", "text/plain", strings.Repeat("a", 129), 413, `{"error":"reading request body: http: request body too large"}`},
+		{"unsupported type", "POST", "/v1/process", "application/xml", "<a/>", 415, `{"error":"unsupported Content-Type \"application/xml\". Use application/json or text/plain"}`},
+		{"wrong method", "GET", "/v1/process", "", "", 405, ""},
+		{"health", "GET", "/healthz", "", "", 200, `{"status":"ok"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if got := strings.TrimSuffix(rec.Body.String(), "\n"); tt.expected != "" && got != tt.expected {
+				t.Errorf("Expected body %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHTTPExplain(t *testing.T) {
+	handler := httpapi.NewHandler(httpapi.Config{})
+	req := httptest.NewRequest("POST", "/v1/process?explain=true", strings.NewReader("so (up) , a owl"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var resp struct {
+		Output string
+		Steps  []struct{ Rule, Before, After string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected a JSON reply, got %q: %v", rec.Body.String(), err)
+	}
+	if resp.Output != "SO, an owl" {
+		t.Errorf("Expected output %q, got %q", "SO, an owl", resp.Output)
+	}
+	expected := []string{
+		"case: so (up) , a owl -> SO , a owl",
+		"punctuation: SO , a owl -> SO, a owl",
+		"articles: SO, a owl -> SO, an owl",
+	}
+	var got []string
+	for _, s := range resp.Steps {
+		got = append(got, s.Rule+": "+s.Before+" -> "+s.After)
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected steps:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	handler := httpapi.NewHandler(httpapi.Config{MaxBodyBytes: 8 << 20, Timeout: time.Nanosecond})
+	req := httptest.NewRequest("POST", "/v1/process", strings.NewReader(strings.Repeat("so exciting (up, 2) ", 100000)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestServeCommand(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	cmd := exec.Command(binary, "serve", "--addr", "127.0.0.1:0")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	// The address is printed once the server is listening on it
+	line, err := bufio.NewReader(stderr).ReadString('\n')
+	addr, ok := strings.CutPrefix(strings.TrimSpace(line), "Listening on ")
+	if err != nil || !ok || strings.HasSuffix(addr, ":0") {
+		t.Fatalf("Expected the bound address, got %q: %v", line, err)
+	}
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err != nil {
+		t.Fatalf("Server not reachable at %s: %v", addr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	cmd.Process.Signal(os.Interrupt)
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
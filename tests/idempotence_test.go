@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var idempotenceInputs = []string{
+	"1E (hex) files were added",
+	"Ready, set, go (up) !",
+	"This is so exciting (up, 2)",
+	"It costs 1,000 dollars , or 1,5 per item",
+	"Time is 10:30 and pi is 3.14 .",
+	"a a a an an a (cap,2) (low,3) orange.",
+	"THIS (low, 4) LINE HAS (up, 2) mixed COMMANDS (low, 5) to test priority.",
+	"He claimed ' i am invincible (up, 2) and immortal ' (cap, 4) ,yet fell to a arrow.",
+	"an house, an hour and a honest heir (up) .",
+	"word (up, 0) stays",
+	"this is a test.\na new line starts here.\na (cap) orange\nbut a (up) apple",
+	"f (hex) (hex)",
+	"10 (bin) (hex)",
+}
+
+func TestIdempotence(t *testing.T) {
+	modes := map[string]processor.Processor{
+		"pipeline": processor.NewPipeline(),
+		"fsm":      processor.NewFSM(),
+		"hybrid":   processor.NewHybrid(),
+	}
+
+	for mode, proc := range modes {
+		for _, input := range idempotenceInputs {
+			t.Run(mode, func(t *testing.T) {
+				if _, err := processor.VerifyIdempotent(proc, input); err != nil {
+					t.Errorf("%s mode:\nInput: %q\n%v", mode, input, err)
+				}
+			})
+		}
+	}
+}
+
+func TestPunctuationKeepsNumberSeparators(t *testing.T) {
+	pipeline := processor.NewPipeline()
+
+	input := "It costs 1,000 dollars ,or 2,5 euro"
+	expected := "It costs 1,000 dollars, or 2,5 euro"
+	if result := pipeline.Process(input); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestCLIVerifyIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputFile := filepath.Join(dir, "input.txt")
+	outputFile := filepath.Join(dir, "output.txt")
+	if err := os.WriteFile(inputFile, []byte("a apple costs 1,000 coins ,not 1E (hex)"), 0644); err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+
+	cmd := exec.Command(binary, "--verify-idempotent", inputFile, outputFile, "pipeline")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("CLI failed: %v\nOutput: %s", err, output)
+	}
+
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(content) != "an apple costs 1,000 coins, not 30" {
+		t.Errorf("Unexpected output %q", string(content))
+	}
+}
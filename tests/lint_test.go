@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []processor.Option
+		expected []string
+	}{
+		{"clean", "He said: 'hello there' and an apple, it's fine.", nil, nil},
+		{"articles", "a apple and an pear", nil, []string{
+			`1:1: warning: "a" should be "an" before "apple" [articles]`,
+			`1:13: warning: "an" should be "a" before "pear" [articles]`,
+		}},
+		{"punctuation", "Hi , there ,ok ? !", nil, []string{
+			`1:3: warning: unexpected space before "," [punctuation]`,
+			`1:11: warning: unexpected space before "," [punctuation]`,
+			`1:13: warning: missing space after "," [punctuation]`,
+			`1:15: warning: unexpected space before "?" [punctuation]`,
+			`1:17: warning: unexpected space before "!" [punctuation]`,
+		}},
+		{"quotes", "' hi ' and 'open\ndon't \"stop", nil, []string{
+			`1:2: warning: unexpected space after "'" [quotes]`,
+			`1:5: warning: unexpected space before "'" [quotes]`,
+			`1:12: warning: quote "'" has no partner [quotes]`,
+			`2:7: warning: quote "\"" has no partner [quotes]`,
+		}},
+		{"markers", "so (up) and (upp) and (see below)", nil, []string{
+			`1:4: warning: marker (up) has not been applied [leftover-markers]`,
+			`1:13: error: unknown marker (upp); did you mean (up)? [unknown-markers]`,
+		}},
+		{"defined markers", "so (shout)", []processor.Option{processor.WithMarkers(map[string]string{"shout": "(up) !"})}, []string{
+			`1:4: warning: marker (shout) has not been applied [leftover-markers]`,
+		}},
+		{"french", "Bonjour ! « salut »", []processor.Option{processor.WithLocale(rules.LocaleFrench)}, []string{
+			`1:8: warning: use narrow no-break space before "!" [punctuation]`,
+			`1:12: warning: use narrow no-break space after "«" [quotes]`,
+			`1:18: warning: use narrow no-break space before "»" [quotes]`,
+		}},
+		{"selected rules", "a apple , ok", []processor.Option{processor.WithRules([]string{"case", "articles"})}, []string{
+			`1:1: warning: "a" should be "an" before "apple" [articles]`,
+		}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, f := range processor.Lint(tt.input, tt.opts...) {
+			got = append(got, f.Diagnostic.String()+" ["+f.Rule+"]")
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestLintProcessedText(t *testing.T) {
+	inputs := []string{
+		"He said : ' hello there ' and a apple ,ok (up) it's fine",
+		"1,000 and 3.14 at 10:30 — then ... ok ? !",
+		"pages 10 – 20 see ( the notes ) late—very late",
+	}
+	for _, mode := range processor.ModeNames {
+		proc, _ := processor.New(mode)
+		for _, input := range inputs {
+			output := proc.Process(input)
+			if findings := processor.Lint(output); len(findings) != 0 {
+				t.Errorf("%s: expected no findings in %q, got %v", mode, output, findings)
+			}
+		}
+	}
+}
+
+func TestCLILint(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	files := map[string]string{
+		"bad.txt":  "a apple (upp)\n",
+		"good.txt": "an apple.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lint := func(args ...string) (string, int) {
+		cmd := exec.Command(binary, append([]string{"lint"}, args...)...)
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return string(output), exitErr.ExitCode()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(output), 0
+	}
+
+	if output, code := lint("good.txt"); output != "" || code != 0 {
+		t.Errorf("Expected no findings and exit 0, got %d: %q", code, output)
+	}
+	expected := "bad.txt:1:1: warning: \"a\" should be \"an\" before \"apple\" [articles]\n" +
+		"bad.txt:1:9: error: unknown marker (upp); did you mean (up)? [unknown-markers]\n"
+	if output, code := lint("good.txt", "bad.txt"); output != expected || code != 1 {
+		t.Errorf("Expected exit 1 and %q, got %d: %q", expected, code, output)
+	}
+	if _, code := lint("missing.txt"); code != 2 {
+		t.Errorf("Expected exit 2 for a missing file, got %d", code)
+	}
+	if _, code := lint("--format", "xml", "bad.txt"); code != 2 {
+		t.Errorf("Expected exit 2 for an unknown format, got %d", code)
+	}
+	if _, code := lint(); code != 2 {
+		t.Errorf("Expected exit 2 without files, got %d", code)
+	}
+
+	output, _ := lint("--format", "json", "bad.txt")
+	var results []map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, output)
+	}
+	if len(results) != 2 || results[0]["file"] != "bad.txt" || results[0]["rule"] != "articles" ||
+		results[0]["line"] != 1.0 || results[0]["column"] != 1.0 || results[0]["severity"] != "warning" {
+		t.Errorf("Unexpected JSON report: %s", output)
+	}
+	if output, _ := lint("--format", "json", "good.txt"); strings.TrimSpace(output) != "[]" {
+		t.Errorf("Expected an empty JSON array, got %q", output)
+	}
+
+	output, _ = lint("--format", "sarif", "bad.txt")
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v\n%s", err, output)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != len(processor.LintRules) {
+		t.Fatalf("Unexpected SARIF log: %s", output)
+	}
+	found := log.Runs[0].Results
+	if len(found) != 2 || found[1].RuleID != "unknown-markers" || found[1].Level != "error" {
+		t.Fatalf("Unexpected SARIF results: %s", output)
+	}
+	location := found[1].Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "bad.txt" || location.Region.StartLine != 1 || location.Region.StartColumn != 9 {
+		t.Errorf("Unexpected SARIF location: %+v", location)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"testing"
+)
+
+func TestLocalePunctuation(t *testing.T) {
+	const nnbsp = "\u202F"
+
+	tests := []struct {
+		name     string
+		locale   rules.Locale
+		input    string
+		expected string
+	}{
+		{"English", rules.LocaleEnglish, "Hello ! Ready ? Yes : now", "Hello! Ready? Yes: now"},
+		{"French_marks", rules.LocaleFrench, "Bonjour! Prêt ? Oui: à 10:30 ; voilà", "Bonjour" + nnbsp + "! Prêt" + nnbsp + "? Oui" + nnbsp + ": à 10:30" + nnbsp + "; voilà"},
+		{"French_combined", rules.LocaleFrench, "Quoi ? ! Non , merci .", "Quoi" + nnbsp + "?! Non, merci."},
+		{"French_guillemets", rules.LocaleFrench, "il dit «bonjour ! » et part", "il dit «" + nnbsp + "bonjour" + nnbsp + "!" + nnbsp + "» et part"},
+		{"French_url", rules.LocaleFrench, "voir http://example.com", "voir http://example.com"},
+		{"German_quotes", rules.LocaleGerman, "er sagt „ hallo “ und » tschüss «", "er sagt „hallo“ und »tschüss«"},
+		{"Spanish_marks", rules.LocaleSpanish, "¿ Qué tal ? ¡ Hola !", "¿Qué tal? ¡Hola!"},
+		{"Spanish_guillemets", rules.LocaleSpanish, "dijo « hola »", "dijo «hola»"},
+	}
+
+	for _, tt := range tests {
+		modes := map[string]processor.Processor{
+			"pipeline": processor.NewPipeline(processor.WithLocale(tt.locale)),
+			"fsm":      processor.NewFSM(processor.WithLocale(tt.locale)),
+			"hybrid":   processor.NewHybrid(processor.WithLocale(tt.locale)),
+		}
+		for mode, proc := range modes {
+			t.Run(mode+"_"+tt.name, func(t *testing.T) {
+				result, err := processor.VerifyIdempotent(proc, tt.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result != tt.expected {
+					t.Errorf("%s mode with %s locale:\nExpected: %q\nGot:      %q", mode, tt.locale, tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	for _, name := range []string{"en", "fr", "de", "es"} {
+		locale, err := rules.ParseLocale(name)
+		if err != nil || locale.String() != name {
+			t.Errorf("ParseLocale(%q) = %v, %v", name, locale, err)
+		}
+	}
+	if _, err := rules.ParseLocale("xx"); err == nil {
+		t.Error("expected an error for an unknown locale")
+	}
+}
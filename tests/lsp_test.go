@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-reloaded/internal/lsp"
+	"go-reloaded/internal/rules"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lspSession sends messages to a new language server and returns what it
+// wrote back, decoded
+func lspSession(t *testing.T, messages ...map[string]interface{}) []map[string]interface{} {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, msg := range messages {
+		msg["jsonrpc"] = "2.0"
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	if err := lsp.NewServer(&out).Serve(&in); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []map[string]interface{}
+	reader := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err == io.EOF {
+			return replies
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatal(err)
+		}
+		var reply map[string]interface{}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, reply)
+	}
+}
+
+const lspURI = "file:///notes.txt"
+
+func lspOpen(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"method": "textDocument/didOpen",
+		"params": map[string]interface{}{"textDocument": map[string]interface{}{"uri": lspURI, "languageId": "plaintext", "version": 1, "text": text}},
+	}
+}
+
+func lspRequest(id int, method string, params map[string]interface{}) map[string]interface{} {
+	params["textDocument"] = map[string]interface{}{"uri": lspURI}
+	return map[string]interface{}{"id": id, "method": method, "params": params}
+}
+
+func lspPosition(line, character int) map[string]interface{} {
+	return map[string]interface{}{"line": line, "character": character}
+}
+
+// lspResult returns the result of the reply to request id
+func lspResult(t *testing.T, replies []map[string]interface{}, id int) interface{} {
+	t.Helper()
+	for _, reply := range replies {
+		if reply["id"] == float64(id) {
+			return reply["result"]
+		}
+	}
+	t.Fatalf("No reply to request %d in %v", id, replies)
+	return nil
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	text := "so exciting (up, 2)\nbad ( up ) and (upp) and (up, x)\nglued(cap) and (Smith, 2003)"
+	replies := lspSession(t, lspOpen(text), map[string]interface{}{"method": "exit"})
+	if len(replies) != 1 || replies[0]["method"] != "textDocument/publishDiagnostics" {
+		t.Fatalf("Expected one publishDiagnostics notification, got %v", replies)
+	}
+
+	var got []string
+	for _, d := range replies[0]["params"].(map[string]interface{})["diagnostics"].([]interface{}) {
+		d := d.(map[string]interface{})
+		start := d["range"].(map[string]interface{})["start"].(map[string]interface{})
+		got = append(got, fmt.Sprintf("%v:%v %v %s", start["line"], start["character"], d["severity"], d["message"]))
+	}
+	expected := []string{
+		"1:4 1 malformed marker ( up ): extra spaces; did you mean (up)?",
+		"1:15 2 unknown marker (upp); did you mean (up)?",
+		"1:25 1 malformed marker (up, x): the count must be a whole number of at least 1",
+		"2:5 2 marker (cap) must be separated from the word before it by a space",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected diagnostics:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestLSPHoverCodeActionsAndCompletion(t *testing.T) {
+	text := "so exciting (up, 2)\nnaïve (cap) and (upp)\nnew ("
+	replies := lspSession(t,
+		map[string]interface{}{"id": 1, "method": "initialize", "params": map[string]interface{}{}},
+		lspOpen(text),
+		lspRequest(2, "textDocument/hover", map[string]interface{}{"position": lspPosition(0, 14)}),
+		lspRequest(3, "textDocument/hover", map[string]interface{}{"position": lspPosition(1, 8)}),
+		lspRequest(4, "textDocument/hover", map[string]interface{}{"position": lspPosition(0, 2)}),
+		lspRequest(5, "textDocument/codeAction", map[string]interface{}{
+			"range":   map[string]interface{}{"start": lspPosition(1, 0), "end": lspPosition(1, 21)},
+			"context": map[string]interface{}{"diagnostics": []interface{}{}},
+		}),
+		lspRequest(6, "textDocument/completion", map[string]interface{}{"position": lspPosition(2, 5)}),
+		lspRequest(7, "textDocument/unknown", map[string]interface{}{}),
+		map[string]interface{}{"id": 8, "method": "shutdown"},
+		map[string]interface{}{"method": "exit"},
+	)
+
+	capabilities := lspResult(t, replies, 1).(map[string]interface{})["capabilities"].(map[string]interface{})
+	if capabilities["hoverProvider"] != true || capabilities["codeActionProvider"] != true || capabilities["completionProvider"] == nil {
+		t.Errorf("Missing capabilities: %v", capabilities)
+	}
+
+	hover := lspResult(t, replies, 2).(map[string]interface{})["contents"].(map[string]interface{})["value"]
+	if expected := "**(up, 2)** converts to uppercase the 2 words before it\n\n`so exciting` → `SO EXCITING`"; hover != expected {
+		t.Errorf("Expected hover %q, got %q", expected, hover)
+	}
+	hover = lspResult(t, replies, 3).(map[string]interface{})["contents"].(map[string]interface{})["value"]
+	if expected := "**(cap)** capitalizes the word before it\n\n`naïve` → `Naïve`"; hover != expected {
+		t.Errorf("Expected hover %q, got %q", expected, hover)
+	}
+	if result := lspResult(t, replies, 4); result != nil {
+		t.Errorf("Expected no hover outside markers, got %v", result)
+	}
+
+	var titles, edits []string
+	for _, action := range lspResult(t, replies, 5).([]interface{}) {
+		action := action.(map[string]interface{})
+		titles = append(titles, action["title"].(string))
+		edit := action["edit"].(map[string]interface{})["changes"].(map[string]interface{})[lspURI].([]interface{})[0].(map[string]interface{})
+		start := edit["range"].(map[string]interface{})["start"].(map[string]interface{})
+		end := edit["range"].(map[string]interface{})["end"].(map[string]interface{})
+		edits = append(edits, fmt.Sprintf("%v:%v-%v:%v %q", start["line"], start["character"], end["line"], end["character"], edit["newText"]))
+	}
+	expectedTitles := []string{"Apply (cap)", "Replace with (up)", "Apply all markers"}
+	expectedEdits := []string{`1:0-1:11 "Naïve"`, `1:16-1:21 "(up)"`, `0:0-2:5 "SO EXCITING\nNaïve and (upp)\nnew ("`}
+	if strings.Join(titles, "|") != strings.Join(expectedTitles, "|") || strings.Join(edits, "|") != strings.Join(expectedEdits, "|") {
+		t.Errorf("Expected actions %q with edits %q\nGot %q with edits %q", expectedTitles, expectedEdits, titles, edits)
+	}
+
+	var labels []string
+	for _, item := range lspResult(t, replies, 6).([]interface{}) {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+	if strings.Join(labels, ",") != strings.Join(rules.MarkerNames, ",") {
+		t.Errorf("Expected completions %v, got %v", rules.MarkerNames, labels)
+	}
+
+	for _, reply := range replies {
+		if reply["id"] == float64(7) {
+			if reply["error"].(map[string]interface{})["code"] != float64(-32601) {
+				t.Errorf("Expected method not found, got %v", reply)
+			}
+		}
+	}
+}
+
+func TestLSPConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".reloaded.yaml"), []byte("markers:\n  shout: up\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "notes.txt"))
+	document := map[string]interface{}{"uri": uri}
+	replies := lspSession(t,
+		map[string]interface{}{
+			"method": "textDocument/didOpen",
+			"params": map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri, "languageId": "plaintext", "version": 1, "text": "hi (shout)\nbye (upp)"}},
+		},
+		map[string]interface{}{"id": 1, "method": "textDocument/hover", "params": map[string]interface{}{"textDocument": document, "position": lspPosition(0, 5)}},
+		map[string]interface{}{"id": 2, "method": "textDocument/codeAction", "params": map[string]interface{}{
+			"textDocument": document,
+			"range":        map[string]interface{}{"start": lspPosition(0, 0), "end": lspPosition(0, 10)},
+			"context":      map[string]interface{}{"diagnostics": []interface{}{}},
+		}},
+		map[string]interface{}{"method": "exit"},
+	)
+
+	var got []string
+	for _, d := range replies[0]["params"].(map[string]interface{})["diagnostics"].([]interface{}) {
+		got = append(got, d.(map[string]interface{})["message"].(string))
+	}
+	if expected := "unknown marker (upp); did you mean (up)?"; strings.Join(got, "\n") != expected {
+		t.Errorf("Expected only the diagnostic %q, got %q", expected, got)
+	}
+
+	hover := lspResult(t, replies, 1).(map[string]interface{})["contents"].(map[string]interface{})["value"]
+	if expected := "**(shout)** is defined as `(up)`"; hover != expected {
+		t.Errorf("Expected hover %q, got %q", expected, hover)
+	}
+
+	var titles, texts []string
+	for _, action := range lspResult(t, replies, 2).([]interface{}) {
+		action := action.(map[string]interface{})
+		titles = append(titles, action["title"].(string))
+		edit := action["edit"].(map[string]interface{})["changes"].(map[string]interface{})[uri].([]interface{})[0].(map[string]interface{})
+		texts = append(texts, edit["newText"].(string))
+	}
+	if strings.Join(titles, "|") != "Apply all markers" || strings.Join(texts, "|") != "HI\nbye (upp)" {
+		t.Errorf("Expected only Apply all markers giving %q, got %q with %q", "HI\nbye (upp)", titles, texts)
+	}
+}
+
+func TestParseMarker(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"up", "(up)"},
+		{"cap, 3", "(cap, 3)"},
+		{"low,2", "(low, 2)"},
+		{"hex", "(hex)"},
+		{"see below", "not a marker"},
+		{"Smith, 2003", "not a marker"},
+		{"a", "not a marker"},
+		{"UP", "unknown marker (UP); did you mean (up)?"},
+		{"cpa, 2", "unknown marker (cpa, 2); did you mean (cap, 2)?"},
+		{"up , 2", "malformed marker (up , 2): extra spaces; did you mean (up, 2)?"},
+		{"hex, 2", "malformed marker (hex, 2): it takes no count; did you mean (hex)?"},
+		{"up, 0", "malformed marker (up, 0): the count must be a whole number of at least 1"},
+		{"up, -1", "malformed marker (up, -1): the count must be a whole number of at least 1"},
+	}
+
+	for _, tt := range tests {
+		marker, err := rules.ParseMarker(tt.content)
+		got := marker.String()
+		if errors.Is(err, rules.ErrNotMarker) {
+			got = "not a marker"
+		} else if err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("ParseMarker(%q): expected %q, got %q", tt.content, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"errors"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const policyInput = "it was (upp) fine and\nloud (up, 2) (lw)\n(Smith, 2003) ok (cap, -2)."
+
+var policyDiagnostics = []string{
+	"1:8: %s: unknown marker (upp); did you mean (up)?",
+	"2:14: %s: unknown marker (lw); did you mean (low)?",
+	"3:18: %s: malformed marker (cap, -2): the count must be a whole number of at least 1",
+}
+
+func TestMarkerPolicies(t *testing.T) {
+	tests := []struct {
+		policy   rules.MarkerPolicy
+		expected string
+	}{
+		{rules.MarkersKeep, "it was (upp) fine AND\nLOUD (lw)\n(Smith, 2003) ok (cap, -2)."},
+		{rules.MarkersStrip, "it was fine AND\nLOUD\n(Smith, 2003) ok."},
+		{rules.MarkersError, "it was (upp) fine AND\nLOUD (lw)\n(Smith, 2003) ok (cap, -2)."},
+	}
+	for _, mode := range processor.ModeNames {
+		for _, tt := range tests {
+			proc, err := processor.New(mode, processor.WithMarkerPolicy(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, diagnostics := proc.ProcessWithDiagnostics(policyInput)
+			if output != tt.expected {
+				t.Errorf("%s %s: expected %q, got %q", mode, tt.policy, tt.expected, output)
+			}
+			var got []string
+			for _, d := range diagnostics {
+				got = append(got, d.String())
+			}
+			var expected []string
+			for _, format := range policyDiagnostics {
+				expected = append(expected, strings.Replace(format, "%s", tt.policy.Severity().String(), 1))
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("%s %s: expected diagnostics %q, got %q", mode, tt.policy, expected, got)
+			}
+			if again := proc.Process(output); again != output {
+				t.Errorf("%s %s: not idempotent: %q became %q", mode, tt.policy, output, again)
+			}
+		}
+	}
+}
+
+func TestStripMarkerSpacing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(upp) start", "start"},
+		{"end (upp)", "end"},
+		{"word (upp).", "word."},
+		{"a (upp) (lw) b", "a b"},
+		{"line\n(upp)\nnext", "line\nnext"},
+		{"line (upp)\n\nnext", "line\n\nnext"},
+	}
+	for _, mode := range processor.ModeNames {
+		proc, _ := processor.New(mode, processor.WithMarkerPolicy(rules.MarkersStrip), processor.WithRules([]string{}))
+		for _, tt := range tests {
+			if got := proc.Process(tt.input); got != tt.expected {
+				t.Errorf("%s %q: expected %q, got %q", mode, tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestParseMarkerPolicy(t *testing.T) {
+	for _, name := range []string{"keep", "strip", "error"} {
+		if policy, err := rules.ParseMarkerPolicy(name); err != nil || policy.String() != name {
+			t.Errorf("ParseMarkerPolicy(%q): got %v, %v", name, policy, err)
+		}
+	}
+	if _, err := rules.ParseMarkerPolicy("drop"); err == nil || err.Error() != `invalid marker policy "drop". Use one of [keep|strip|error]` {
+		t.Errorf("Expected an error for an unknown policy, got %v", err)
+	}
+	if _, err := config.Parse("x.yaml", []byte("unknown-markers: drop\n")); err == nil {
+		t.Error("Expected the config to reject an unknown marker policy")
+	}
+}
+
+func TestCLIUnknownMarkers(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("so (upp) loud (up)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(binary, "--unknown-markers", "strip", "in.txt", "out.txt")
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("CLI failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(content) != "so LOUD\n" {
+		t.Errorf("Expected the marker to be stripped, got %q", content)
+	}
+	if stderr.String() != "in.txt:1:4: warning: unknown marker (upp); did you mean (up)?\n" {
+		t.Errorf("Unexpected diagnostics: %q", stderr.String())
+	}
+
+	os.Remove(filepath.Join(dir, "out.txt"))
+	cmd = exec.Command(binary, "--unknown-markers", "error", "in.txt", "out.txt")
+	cmd.Dir = dir
+	stderr.Reset()
+	cmd.Stderr = &stderr
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("Expected exit code 4, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "in.txt:1:4: error: unknown marker (upp)") {
+		t.Errorf("Expected an error diagnostic, got %q", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.txt")); !os.IsNotExist(err) {
+		t.Error("Expected no output file to be written")
+	}
+}
+
+func TestInapplicableMarkers(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		stripped    string
+		diagnostics string
+	}{
+		{"zz (hex) now", "zz (hex) now", "zz now", `1:4: %s: marker (hex) cannot convert "zz", which is not a hexadecimal number`},
+		{"and (hex)", "and (hex)", "and", `1:5: %s: marker (hex) cannot convert "and", which is not a hexadecimal number`},
+		{"zz (bin)", "zz (bin)", "zz", `1:4: %s: marker (bin) cannot convert "zz", which is not a binary number`},
+		{"1e, (hex) z", "1e, (hex) z", "1e, z", "1:5: %s: marker (hex) has no hexadecimal number before it"},
+		{"(up)", "(up)", "", "1:1: %s: marker (up) has no word before it"},
+		{"\n(up) x", "\n(up) x", "\nx", "2:1: %s: marker (up) has no word before it"},
+		{"word(up) x", "word(up) x", "word x", "1:5: %s: marker (up) must be separated from the word before it by a space"},
+		{"x (up)y", "x (up)y", "x y", "1:3: %s: marker (up) must be separated from the word after it"},
+		{"(low:end) (cap)", "(low:end) (cap)", "", "1:1: %s: marker (low:end) has no matching (low:begin) before it\n1:11: %s: marker (cap) has no word before it"},
+		{"hello (up>)", "hello (up>)", "hello", "1:7: %s: marker (up>) must be followed by a space and the words it applies to"},
+		{"x (up:begin) open", "x (up:begin) open", "x open", "1:3: %s: marker (up:begin) has no matching (up:end) after it"},
+		{"I can't (up) go", "I CAN'T go", "I CAN'T go", ""},
+		{"it's 1e (hex)", "it's 30", "it's 30", ""},
+		{"ff (up) (hex) x", "255 x", "255 x", ""},
+	}
+	for _, mode := range processor.ModeNames {
+		for _, policy := range []rules.MarkerPolicy{rules.MarkersKeep, rules.MarkersStrip, rules.MarkersError} {
+			proc, _ := processor.New(mode, processor.WithMarkerPolicy(policy))
+			for _, tt := range tests {
+				expected := tt.expected
+				if policy == rules.MarkersStrip {
+					expected = tt.stripped
+				}
+				output, diagnostics := proc.ProcessWithDiagnostics(tt.input)
+				var got []string
+				for _, d := range diagnostics {
+					got = append(got, d.String())
+				}
+				diagnostic := strings.ReplaceAll(tt.diagnostics, "%s", policy.Severity().String())
+				if output != expected || strings.Join(got, "\n") != diagnostic {
+					t.Errorf("%s %s %q: expected %q with %q, got %q with %q", mode, policy, tt.input, expected, diagnostic, output, got)
+				}
+			}
+		}
+	}
+
+	// Markers of disabled rules are left alone without a diagnostic
+	proc, _ := processor.New("fsm", processor.WithRules([]string{"case"}))
+	if output, diagnostics := proc.ProcessWithDiagnostics("zz (hex) now"); output != "zz (hex) now" || len(diagnostics) != 0 {
+		t.Errorf("Expected the number marker to be left alone, got %q with %v", output, diagnostics)
+	}
+}
+
+// engineParityInputs are texts where the engines once disagreed
+var engineParityInputs = []string{
+	"f (hex) (hex)",
+	"3.14 (hex) (up)",
+	"x '\n\n(up) y",
+	"( apple ? (up)",
+	"a (up>, 2) (up, 2) b",
+	"(low:end) (cap) ' (up, 2)",
+	"hi\n\n(up, 2) there",
+	"hi )\n\n(up) there",
+	"x (up)y and z(up) w",
+	"'quoted (up) words' (cap, 2)",
+	"one\n(up)\n\n(cap) two",
+	"(up>) (hex) 1e ff (hex)",
+	"a (up:begin) b (low) (up:end) c",
+	"1e (up) (hex) ,then (bin)",
+}
+
+func TestEngineParity(t *testing.T) {
+	scopes := []rules.Scope{rules.ScopeUnbounded, rules.ScopeParagraph, rules.ScopeLine, rules.ScopeSentence}
+	for _, scope := range scopes {
+		for _, policy := range []rules.MarkerPolicy{rules.MarkersKeep, rules.MarkersStrip} {
+			pipeline := processor.NewPipeline(processor.WithScope(scope), processor.WithMarkerPolicy(policy))
+			for _, mode := range processor.ModeNames {
+				proc, _ := processor.New(mode, processor.WithScope(scope), processor.WithMarkerPolicy(policy))
+				for _, input := range engineParityInputs {
+					expected, expectedDiagnostics := pipeline.ProcessWithDiagnostics(input)
+					output, diagnostics := proc.ProcessWithDiagnostics(input)
+					if output != expected || !reflect.DeepEqual(diagnostics, expectedDiagnostics) {
+						t.Errorf("%s mode, %s scope, %s markers, %q: expected %q with %v, got %q with %v", mode, scope, policy, input, expected, expectedDiagnostics, output, diagnostics)
+					}
+				}
+			}
+		}
+	}
+}
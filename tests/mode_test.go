@@ -14,6 +14,7 @@ func TestPipelineMode(t *testing.T) {
 	}{
 		{"Hex conversion", "1E (hex) files", "30 files"},
 		{"Binary conversion", "10 (bin) years", "2 years"},
+		{"Chained number markers", "f (hex) (hex) and 10 (bin) (hex)", "21 and 2"},
 		{"Uppercase", "go (up)", "GO"},
 		{"Lowercase", "LOUD (low)", "loud"},
 		{"Capitalize", "bridge (cap)", "Bridge"},
@@ -44,6 +45,7 @@ func TestFSMMode(t *testing.T) {
 	}{
 		{"Hex conversion", "1E (hex) files", "30 files"},
 		{"Binary conversion", "10 (bin) years", "2 years"},
+		{"Chained number markers", "f (hex) (hex) and 10 (bin) (hex)", "21 and 2"},
 		{"Uppercase", "go (up)", "GO"},
 		{"Lowercase", "LOUD (low)", "loud"},
 		{"Capitalize", "bridge (cap)", "Bridge"},
@@ -74,6 +76,7 @@ func TestHybridMode(t *testing.T) {
 	}{
 		{"Hex conversion", "1E (hex) files", "30 files"},
 		{"Binary conversion", "10 (bin) years", "2 years"},
+		{"Chained number markers", "f (hex) (hex) and 10 (bin) (hex)", "21 and 2"},
 		{"Uppercase", "go (up)", "GO"},
 		{"Lowercase", "LOUD (low)", "loud"},
 		{"Capitalize", "bridge (cap)", "Bridge"},
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"strings"
+	"testing"
+)
+
+func TestParallelMatchesSequential(t *testing.T) {
+	inputs := []string{
+		"",
+		"single paragraph with 1E (hex) files",
+		"first paragraph , with a apple (up) .\n\nsecond ' quoted ' one (cap)\n\n\nthird 10 (bin) !",
+		"blank lines with spaces\n   \t\n  indented a (cap) orange\n\n",
+		"ends with a\n\napple split is unsafe",
+		"next starts with punctuation\n\n, so no split",
+		"marker after the split 1E\n\n(hex) stays joined",
+		"open ' quote\n\nclosed ' later\n\nand a honest man",
+		"unclosed (marker\n\nup) in fsm",
+		"one (up:begin) two\n\nthree (up:end) four",
+		"one (up>, 3)\n\nthree four",
+		"don't (cap:begin) split\n\nit's (cap:end) open",
+		benchmarkInput(1 << 14),
+	}
+
+	modes := map[string]func() processor.Processor{
+		"pipeline": func() processor.Processor { return processor.NewPipeline() },
+		"fsm":      func() processor.Processor { return processor.NewFSM() },
+		"hybrid":   func() processor.Processor { return processor.NewHybrid() },
+	}
+
+	for mode, newProcessor := range modes {
+		sequential := newProcessor()
+		parallel := processor.NewParallel(newProcessor, 4)
+		for _, input := range inputs {
+			expected := sequential.Process(input)
+			if result := parallel.Process(input); result != expected {
+				t.Errorf("%s mode differs from sequential:\nInput:    %q\nExpected: %q\nGot:      %q", mode, input, expected, result)
+			}
+		}
+	}
+}
+
+func TestParallelKeepsParagraphOrder(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 100; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("word ", i)+"end (up)")
+	}
+	input := strings.Join(paragraphs, "\n\n")
+
+	parallel := processor.NewParallel(func() processor.Processor { return processor.NewPipeline() }, 8)
+	expected := processor.NewPipeline().Process(input)
+	if result := parallel.Process(input); result != expected {
+		t.Errorf("Parallel output is out of order")
+	}
+}
+
+func TestParallelWhitespacePolicy(t *testing.T) {
+	inputs := []string{
+		"a b\n\nc d",
+		"a  b  \n\n\n  c\td (up)",
+		"crlf a\r\n\r\nb\r\n \r\nc",
+		"lone cr\r\rstays\n\t\nsplit",
+	}
+	policies := []rules.Whitespace{rules.WhitespaceCollapse, rules.WhitespaceLF, rules.WhitespaceCRLF}
+
+	for _, policy := range policies {
+		modes := map[string]func() processor.Processor{
+			"pipeline": func() processor.Processor { return processor.NewPipeline(processor.WithWhitespace(policy)) },
+			"fsm":      func() processor.Processor { return processor.NewFSM(processor.WithWhitespace(policy)) },
+			"hybrid":   func() processor.Processor { return processor.NewHybrid(processor.WithWhitespace(policy)) },
+		}
+		for mode, newProcessor := range modes {
+			parallel := processor.NewParallel(newProcessor, 4)
+			for _, input := range inputs {
+				expected := newProcessor().Process(input)
+				if result := parallel.Process(input); result != expected {
+					t.Errorf("%s mode with %s whitespace differs from sequential:\nInput:    %q\nExpected: %q\nGot:      %q", mode, policy, input, expected, result)
+				}
+			}
+		}
+	}
+
+	// Without the whitespace rule the separators are kept as they are
+	newProcessor := func() processor.Processor {
+		return processor.NewPipeline(processor.WithWhitespace(rules.WhitespaceCRLF), processor.WithRules([]string{"case"}))
+	}
+	input := "a b\n\nc d"
+	if result := processor.NewParallel(newProcessor, 2).Process(input); result != input {
+		t.Errorf("Expected separators untouched without the whitespace rule, got %q", result)
+	}
+}
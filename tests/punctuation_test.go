@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"go-reloaded/internal/tokenizer"
+	"testing"
+)
+
+func TestExtendedPunctuation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Em_dash_spaced", "it was late—very late —  and dark", "it was late — very late — and dark"},
+		{"Em_dash_line_start", "— Who is there?\n—Me.", "— Who is there?\n— Me."},
+		{"En_dash_range", "pages 10 – 20 and 1990–2000", "pages 10–20 and 1990–2000"},
+		{"En_dash_words", "London–Paris train", "London – Paris train"},
+		{"Brackets", "see ( the notes ) and [ 1 ]", "see (the notes) and [1]"},
+		{"Bracket_marker_kept", "word ( up ) here", "word ( up ) here"},
+		{"Unicode_ellipsis", "wait …", "wait…"},
+		{"Interrobang", "really ‽", "really‽"},
+		{"Decimals_and_times", "pi is 3.14 at 10:30 ,costing 1,000 ,ok", "pi is 3.14 at 10:30, costing 1,000, ok"},
+	}
+
+	modes := map[string]processor.Processor{
+		"pipeline": processor.NewPipeline(),
+		"fsm":      processor.NewFSM(),
+		"hybrid":   processor.NewHybrid(),
+	}
+
+	for _, tt := range tests {
+		for mode, proc := range modes {
+			t.Run(mode+"_"+tt.name, func(t *testing.T) {
+				result, err := processor.VerifyIdempotent(proc, tt.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result != tt.expected {
+					t.Errorf("%s mode:\nInput:    %q\nExpected: %q\nGot:      %q", mode, tt.input, tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestTokenizerUsesPunctuationTable(t *testing.T) {
+	tokens := tokenizer.NewTokenizer().Tokenize("so—[yes]…‽")
+	for _, tok := range tokens {
+		r := []rune(tok.Value)[0]
+		if rules.IsPunctuation(r) != (tok.Type == tokenizer.Punctuation) {
+			t.Errorf("token %q has type %d", tok.Value, tok.Type)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"strings"
+	"testing"
+)
+
+// realtimeOptions are the settings the real-time engine is checked with
+var realtimeOptions = []struct {
+	name string
+	opts []processor.Option
+}{
+	{"default", nil},
+	{"paragraph", []processor.Option{processor.WithScope(rules.ScopeParagraph)}},
+	{"line_collapse", []processor.Option{processor.WithScope(rules.ScopeLine), processor.WithWhitespace(rules.WhitespaceCollapse)}},
+	{"sentence_crlf", []processor.Option{processor.WithScope(rules.ScopeSentence), processor.WithWhitespace(rules.WhitespaceCRLF)}},
+	{"paragraph_fr", []processor.Option{processor.WithScope(rules.ScopeParagraph), processor.WithLocale(rules.LocaleFrench)}},
+}
+
+// realtimeParityInputs covers every marker form and the rules that look at
+// the text around a word
+var realtimeParityInputs = append([]string{
+	"hello (up) world",
+	"1E (hex) files and 10 (bin) years",
+	"so exciting (up, 2) and quiet (low, 1)",
+	"three little words (cap, 3) (up, 2)",
+	"too far (up, 10)",
+	"word (up, 0) stays and (cap) alone",
+	"a (cap) apple",
+	"He said ' hello there ' , then left !",
+	"wait ... what ? ! really",
+	"first paragraph (up, 2)\n\nsecond (cap) one\n\n\n(up) starts a paragraph",
+	"ends with a\n\napple",
+	"ends with a quote '\n\n  still quoted ' here",
+	"punctuation waits\n\n, for the next paragraph",
+	"crlf line (up)\r\n\r\nnext  paragraph (up, 5)\r\n",
+	"  indented\n\n\ttabs (up)\n",
+	"Bonjour ! Prêt ? « oui »",
+	"an unfinished (up",
+	"one (up:begin) two\n\nthree (up:end) four",
+	"one (up>, 3)\n\nthree four",
+	"hi\n\n(up, 2) there",
+	"one (up)\n\n(cap)\n\ntwo",
+	"a (up, 2)\n\napple",
+}, idempotenceInputs...)
+
+// TestRealtimeParity checks that feeding text to the real-time engine one
+// character at a time gives the same final output as Pipeline.Process
+func TestRealtimeParity(t *testing.T) {
+	for _, opts := range realtimeOptions {
+		pipeline := processor.NewPipeline(opts.opts...)
+		for _, input := range realtimeParityInputs {
+			t.Run(opts.name, func(t *testing.T) {
+				fsm := processor.NewRealtimeFSM(opts.opts...)
+				var streamed strings.Builder
+				for _, r := range input {
+					streamed.WriteString(fsm.ProcessChar(r))
+				}
+				if streamed.String() != fsm.Output() {
+					t.Errorf("Output %q differs from streamed output %q", fsm.Output(), streamed.String())
+				}
+				streamed.WriteString(fsm.Flush())
+
+				if expected := pipeline.Process(input); streamed.String() != expected {
+					t.Errorf("Input: %q\nExpected: %q\nGot:      %q", input, expected, streamed.String())
+				}
+			})
+		}
+	}
+}
+
+// TestRealtimeParityEdits checks that text typed with mistakes and fixed
+// with backspace ends up with the same output as the clean text
+func TestRealtimeParityEdits(t *testing.T) {
+	for _, opts := range realtimeOptions {
+		pipeline := processor.NewPipeline(opts.opts...)
+		for _, input := range realtimeParityInputs {
+			t.Run(opts.name, func(t *testing.T) {
+				fsm := processor.NewRealtimeFSM(opts.opts...)
+				for i, r := range input {
+					if i%3 == 0 {
+						fsm.InsertChar('(')
+						fsm.DeleteChar()
+					}
+					fsm.InsertChar(r)
+				}
+				if fsm.Text() != input {
+					t.Fatalf("Expected text %q, got %q", input, fsm.Text())
+				}
+				if got, expected := realtimeResult(fsm), pipeline.Process(input); got != expected {
+					t.Errorf("Input: %q\nExpected: %q\nGot:      %q", input, expected, got)
+				}
+			})
+		}
+	}
+}
+
+func TestRealtimeCommitsParagraphs(t *testing.T) {
+	fsm := processor.NewRealtimeFSM(processor.WithScope(rules.ScopeParagraph))
+	var streamed strings.Builder
+	for _, r := range "one (up)\n\ntwo (cap)\n\nthree" {
+		streamed.WriteString(fsm.ProcessChar(r))
+	}
+	if expected := "ONE\n\nTwo\n\n"; streamed.String() != expected {
+		t.Errorf("Expected final output %q before the end, got %q", expected, streamed.String())
+	}
+
+	unbounded := processor.NewRealtimeFSM()
+	for _, r := range "one\n\ntwo (up, 2)" {
+		if out := unbounded.ProcessChar(r); out != "" {
+			t.Errorf("Expected no final output with an unbounded scope, got %q", out)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"math/rand"
+	"testing"
+)
+
+// realtimeResult returns the final output of fsm for its whole input
+func realtimeResult(fsm *processor.RealtimeFSM) string {
+	return fsm.Output() + fsm.Flush()
+}
+
+func TestRealtimeApplyEdit(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  string
+		offset   int
+		delete   int
+		insert   string
+		text     string
+		expected string
+	}{
+		{"Insert_marker", "hello world ", 5, 0, " (up)", "hello (up) world ", "HELLO world "},
+		{"Delete_marker", "hello (up) world ", 5, 5, "", "hello world ", "hello world "},
+		{"Change_marker", "ff (hex) and 1a (hex) ", 4, 3, "bin", "ff (bin) and 1a (hex) ", "ff (bin) and 26 "},
+		{"Open_quote", "say it now ", 4, 0, "' ", "say ' it now ", "say ' it now "},
+		{"Close_marker", "word (up now", 8, 0, ")", "word (up) now", "WORD now"},
+		{"Replace_all", "abc", 0, 3, "x (cap) ", "x (cap) ", "X "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsm := processor.NewRealtimeFSM()
+			for _, r := range tt.initial {
+				fsm.ProcessChar(r)
+			}
+			if err := fsm.ApplyEdit(tt.offset, tt.delete, tt.insert); err != nil {
+				t.Fatal(err)
+			}
+			if fsm.Text() != tt.text {
+				t.Errorf("Expected text %q, got %q", tt.text, fsm.Text())
+			}
+			if got := realtimeResult(fsm); got != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRealtimeApplyEditOutOfRange(t *testing.T) {
+	fsm := processor.NewRealtimeFSM()
+	fsm.ProcessChar('a')
+	for _, edit := range [][2]int{{-1, 0}, {0, 2}, {2, 0}, {0, -1}} {
+		if err := fsm.ApplyEdit(edit[0], edit[1], "x"); err == nil {
+			t.Errorf("Expected an error for edit %v", edit)
+		}
+	}
+}
+
+func TestRealtimeCursor(t *testing.T) {
+	fsm := processor.NewRealtimeFSM()
+	for _, r := range "ab cd " {
+		fsm.ProcessChar(r)
+	}
+
+	fsm.MoveCursor(-4)
+	for _, r := range " (up)" {
+		fsm.InsertChar(r)
+	}
+	if fsm.Text() != "ab (up) cd " || fsm.Cursor() != 7 {
+		t.Fatalf("Expected %q with cursor 7, got %q with cursor %d", "ab (up) cd ", fsm.Text(), fsm.Cursor())
+	}
+
+	fsm.SetCursor(100)
+	if !fsm.DeleteChar() || fsm.Text() != "ab (up) cd" || fsm.Cursor() != 10 {
+		t.Fatalf("Expected %q with cursor 10, got %q with cursor %d", "ab (up) cd", fsm.Text(), fsm.Cursor())
+	}
+	if got := fsm.Preview() + fsm.GetCurrentBuffer(); got != "AB cd" {
+		t.Errorf("Expected preview %q, got %q", "AB cd", got)
+	}
+
+	fsm.SetCursor(-3)
+	if fsm.DeleteChar() || fsm.Cursor() != 0 {
+		t.Errorf("Expected no deletion at the start, cursor %d", fsm.Cursor())
+	}
+}
+
+func TestRealtimePending(t *testing.T) {
+	tests := []struct {
+		input   string
+		preview string
+		pending string
+	}{
+		{"go (up) now", "GO ", "now"},
+		{"word (up", "word ", "(up"},
+		{"it is ' quoted", "it is ", "' quoted"},
+		{"don't stop", "don't ", "stop"},
+	}
+
+	for _, tt := range tests {
+		fsm := processor.NewRealtimeFSM()
+		for _, r := range tt.input {
+			fsm.ProcessChar(r)
+		}
+		if preview, pending := fsm.Preview(), fsm.GetCurrentBuffer(); preview != tt.preview || pending != tt.pending {
+			t.Errorf("Input %q: expected preview %q and pending %q, got %q and %q", tt.input, tt.preview, tt.pending, preview, pending)
+		}
+	}
+}
+
+// TestRealtimeEditsMatchPipeline checks that after random edits the final
+// output is the same as processing the edited text in one go
+func TestRealtimeEditsMatchPipeline(t *testing.T) {
+	pieces := []string{"a", "word", " ", "\n", "\n\n", "(", ")", "up", "hex", "1f", "'", " (cap)", " (up, 2)", " (bin)", "10", ".", "!"}
+	for _, opts := range realtimeOptions {
+		rng := rand.New(rand.NewSource(1))
+		fsm := processor.NewRealtimeFSM(opts.opts...)
+		pipeline := processor.NewPipeline(opts.opts...)
+
+		for i := 0; i < 1000; i++ {
+			length := len([]rune(fsm.Text()))
+			offset := rng.Intn(length + 1)
+			del := 0
+			if rest := length - offset; rest > 0 && rng.Intn(3) == 0 {
+				del = rng.Intn(rest%4 + 1)
+			}
+			insert := ""
+			if rng.Intn(4) != 0 {
+				insert = pieces[rng.Intn(len(pieces))]
+			}
+			if err := fsm.ApplyEdit(offset, del, insert); err != nil {
+				t.Fatal(err)
+			}
+
+			// Output that is final must stay a prefix of the result
+			expected := pipeline.Process(fsm.Text())
+			if output := fsm.Output(); expected[:min(len(output), len(expected))] != output {
+				t.Fatalf("%s: after edit %d at %d deleting %d inserting %q on %q:\nExpected prefix of: %q\nGot:                %q",
+					opts.name, i, offset, del, insert, fsm.Text(), expected, output)
+			}
+		}
+
+		text := fsm.Text()
+		if got, expected := realtimeResult(fsm), pipeline.Process(text); got != expected {
+			t.Errorf("%s: for %q\nExpected: %q\nGot:      %q", opts.name, text, expected, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"errors"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"go-reloaded/reloaded"
+	"strings"
+	"testing"
+)
+
+func TestReloadedModes(t *testing.T) {
+	input := "it was a apple (up, 2) , said 'the' owl (cap)\n\n1E (hex) files"
+	expected := processor.NewPipeline().Process(input)
+
+	for _, mode := range reloaded.Modes() {
+		proc, err := reloaded.New(mode)
+		if err != nil {
+			t.Fatalf("New(%q): %v", mode, err)
+		}
+		if got := proc.Process(input); got != expected {
+			t.Errorf("Mode %s: expected %q, got %q", mode, expected, got)
+		}
+
+		parallel, err := reloaded.NewParallel(mode, 2)
+		if err != nil {
+			t.Fatalf("NewParallel(%q): %v", mode, err)
+		}
+		if got := parallel.Process(input); got != expected {
+			t.Errorf("Parallel mode %s: expected %q, got %q", mode, expected, got)
+		}
+		if got := parallel.Explain(input).Output; got != expected {
+			t.Errorf("Parallel mode %s explained: expected %q, got %q", mode, expected, got)
+		}
+	}
+
+	for _, newProcessor := range []func(reloaded.Mode) (reloaded.Processor, error){
+		func(mode reloaded.Mode) (reloaded.Processor, error) { return reloaded.New(mode) },
+		func(mode reloaded.Mode) (reloaded.Processor, error) { return reloaded.NewParallel(mode, 0) },
+	} {
+		if _, err := newProcessor("fast"); err == nil || err.Error() != `invalid mode "fast". Use one of [pipeline|fsm|hybrid]` {
+			t.Errorf("Expected an invalid mode error, got %v", err)
+		}
+	}
+}
+
+func TestReloadedOptions(t *testing.T) {
+	scope, err := reloaded.ParseScope("line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc, err := reloaded.New(reloaded.FSM,
+		reloaded.WithScope(scope),
+		reloaded.WithWhitespace(reloaded.WhitespaceCollapse),
+		reloaded.WithLocale(reloaded.LocaleFrench))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, diagnostics := proc.ProcessWithDiagnostics("un\ndeux  trois (up, 3) !")
+	if expected := "un\nDEUX TROIS !"; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Severity != reloaded.Warning || diagnostics[0].Line != 2 {
+		t.Errorf("Expected one warning on line 2, got %v", diagnostics)
+	}
+}
+
+func TestReloadedExplain(t *testing.T) {
+	proc, _ := reloaded.New(reloaded.Pipeline)
+	explanation := proc.Explain("so (up) , a owl")
+
+	var rules []string
+	for _, step := range explanation.Steps {
+		rules = append(rules, step.Rule)
+	}
+	if explanation.Output != "SO, an owl" || strings.Join(rules, ",") != "case,punctuation,articles" {
+		t.Errorf("Expected SO, an owl from case,punctuation,articles; got %q from %v", explanation.Output, rules)
+	}
+}
+
+func TestReloadedMarkers(t *testing.T) {
+	var names []string
+	for _, marker := range reloaded.Markers() {
+		names = append(names, marker.Name)
+		if marker.Description == "" {
+			t.Errorf("Marker %s has no description", marker.Name)
+		}
+		if takesCount := marker.Name != "hex" && marker.Name != "bin"; marker.TakesCount != takesCount {
+			t.Errorf("Marker %s: expected TakesCount %v", marker.Name, takesCount)
+		}
+	}
+	if strings.Join(names, ",") != "hex,bin,up,low,cap" {
+		t.Errorf("Expected markers hex,bin,up,low,cap, got %v", names)
+	}
+
+	if marker, err := reloaded.ParseMarker("cap, 3"); err != nil || marker.Name != "cap" || marker.Count != 3 {
+		t.Errorf("Expected (cap, 3), got %v, %v", marker, err)
+	}
+	if _, err := reloaded.ParseMarker("see below"); !errors.Is(err, reloaded.ErrNotMarker) {
+		t.Errorf("Expected ErrNotMarker, got %v", err)
+	}
+	var markerErr *reloaded.MarkerError
+	if _, err := reloaded.ParseMarker("upp"); !errors.As(err, &markerErr) || markerErr.Suggestion != "(up)" {
+		t.Errorf("Expected a MarkerError suggesting (up), got %v", err)
+	}
+}
+
+func TestReloadedStream(t *testing.T) {
+	input := "it was a apple (up, 2) , ok"
+	stream, err := reloaded.NewStream(reloaded.WithScope(reloaded.ScopeParagraph))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output strings.Builder
+	for _, char := range input {
+		output.WriteString(stream.ProcessChar(char))
+	}
+	if preview := stream.Preview(); !strings.HasPrefix(preview, "it was AN APPLE") {
+		t.Errorf("Expected the preview to show the applied marker, got %q", preview)
+	}
+	output.WriteString(stream.Flush())
+
+	if expected := processor.NewPipeline().Process(input); output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+}
+
+// TestReloadedSettings checks that every setting the processors have can be
+// named through the public types, and means the same there
+func TestReloadedSettings(t *testing.T) {
+	for s := rules.Scope(0); s.String() != ""; s++ {
+		if scope, err := reloaded.ParseScope(s.String()); err != nil || scope.String() != s.String() {
+			t.Errorf("ParseScope(%q) = %v, %v", s, scope, err)
+		}
+	}
+	for w := rules.Whitespace(0); w.String() != ""; w++ {
+		if policy, err := reloaded.ParseWhitespace(w.String()); err != nil || policy.String() != w.String() {
+			t.Errorf("ParseWhitespace(%q) = %v, %v", w, policy, err)
+		}
+	}
+	for p := rules.MarkerPolicy(0); p.String() != ""; p++ {
+		if policy, err := reloaded.ParseMarkerPolicy(p.String()); err != nil || policy.String() != p.String() {
+			t.Errorf("ParseMarkerPolicy(%q) = %v, %v", p, policy, err)
+		}
+	}
+	for l := rules.Locale(0); l.String() != ""; l++ {
+		if locale, err := reloaded.ParseLocale(l.String()); err != nil || locale.String() != l.String() {
+			t.Errorf("ParseLocale(%q) = %v, %v", l, locale, err)
+		}
+	}
+	if _, err := reloaded.ParseScope("page"); err == nil || err.Error() != `invalid scope "page". Use one of [unbounded|line|sentence|paragraph]` {
+		t.Errorf("Expected an invalid scope error, got %v", err)
+	}
+
+	proc, _ := reloaded.New(reloaded.Pipeline,
+		reloaded.WithWhitespace(reloaded.WhitespaceCRLF),
+		reloaded.WithMarkerPolicy(reloaded.MarkersError))
+	output, diagnostics := proc.ProcessWithDiagnostics("so (upp)\nloud (up)")
+	if output != "so (upp)\r\nLOUD" {
+		t.Errorf("Expected CRLF line endings, got %q", output)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].String() != "1:4: error: unknown marker (upp); did you mean (up)?" {
+		t.Errorf("Expected an error for (upp), got %v", diagnostics)
+	}
+
+	invalid := []reloaded.Option{
+		reloaded.WithScope(reloaded.Scope(42)),
+		reloaded.WithWhitespace(reloaded.Whitespace(-1)),
+		reloaded.WithLocale(reloaded.Locale(9)),
+		reloaded.WithMarkerPolicy(reloaded.MarkerPolicy(3)),
+	}
+	for _, opt := range invalid {
+		if _, err := reloaded.New(reloaded.Pipeline, opt); err == nil {
+			t.Error("Expected New to reject an unknown option value")
+		}
+		if _, err := reloaded.NewParallel(reloaded.FSM, 2, opt); err == nil {
+			t.Error("Expected NewParallel to reject an unknown option value")
+		}
+		if _, err := reloaded.NewStream(opt); err == nil {
+			t.Error("Expected NewStream to reject an unknown option value")
+		}
+	}
+	if _, err := reloaded.New(reloaded.Pipeline, reloaded.WithScope(reloaded.Scope(42))); err == nil || err.Error() != "invalid scope 42. Use one of the Scope constants" {
+		t.Errorf("Expected an invalid scope error, got %v", err)
+	}
+
+	if marker, err := reloaded.ParseMarker("low>, 2"); err != nil || marker.Direction != reloaded.Forward || marker.String() != "(low>, 2)" {
+		t.Errorf("Expected (low>, 2), got %v, %v", marker, err)
+	}
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"bytes"
+	"go-reloaded/internal/repl"
+	"strings"
+	"testing"
+)
+
+// runSession feeds keys to a new REPL session and returns what it drew
+func runSession(t *testing.T, keys string) (string, *repl.Session) {
+	t.Helper()
+	var out bytes.Buffer
+	session := repl.New(strings.NewReader(keys), &out)
+	if err := session.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), session
+}
+
+func TestREPL(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected []string
+	}{
+		{"Process_on_enter", "hello (up) world\r", []string{"Output:  HELLO world\r\n"}},
+		{"Backspace", "abc\x7f\x7fx (up)\r", []string{"Output:  AX\r\n"}},
+		{"History", "one (up)\rtwo\r\x1b[A\x1b[A\r", []string{"Output:  ONE\r\n", "Output:  two\r\n", "Output:  ONE\r\n"}},
+		{"Multi_line", "a (cap)\nb (up)\r", []string{"Output:  A\r\n         B\r\n"}},
+		{"Alt_enter", "x\x1b\ry\r", []string{"Output:  x\r\n         y\r\n"}},
+		{"Cursor_movement", "ac\x1b[Db\x1b[Hx\x7f\x1b[F (up)\r", []string{"Output:  ABC\r\n"}},
+		{"Clear_line", "oops\x15fine\r", []string{"Output:  fine\r\n"}},
+		{"Pending_marker_highlighted", "word (up", []string{"Preview: word \x1b[33m(up\x1b[0m"}},
+		{"Live_preview", "go (up) now", []string{"Preview: GO \x1b[33mnow\x1b[0m"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := runSession(t, tt.keys)
+			// Expected pieces must appear in order
+			rest := out
+			for _, want := range tt.expected {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("Expected output to contain %q in order\nGot: %q", want, out)
+				}
+				rest = rest[i+len(want):]
+			}
+		})
+	}
+}
+
+func TestREPLQuit(t *testing.T) {
+	out, session := runSession(t, "one\rquit\rtwo\r")
+	if strings.Contains(out, "two") {
+		t.Errorf("Expected session to end at quit, got %q", out)
+	}
+	if history := session.History(); len(history) != 1 || history[0] != "one" {
+		t.Errorf("Expected history [one], got %q", history)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"errors"
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/processor"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected []string
+		err      string
+	}{
+		{"case,numbers", []string{"case", "numbers"}, ""},
+		{" numbers , case,articles ", []string{"numbers", "case", "articles"}, ""},
+		{"whitespace,articles,quotes", []string{"whitespace", "articles", "quotes"}, ""},
+		{"", []string{}, ""},
+		{"articles,case", nil, `rule "articles" must come after "case"`},
+		{"case,spelling,case", nil, "unknown rule \"spelling\". Use some of [case|numbers|quotes|punctuation|articles|whitespace]\nrule \"case\" is listed twice"},
+	}
+	for _, tt := range tests {
+		names, err := processor.ParseRules(tt.list)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("ParseRules(%q): unexpected error %v", tt.list, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("ParseRules(%q): expected error %q, got %v", tt.list, tt.err, err)
+		case !reflect.DeepEqual(names, tt.expected):
+			t.Errorf("ParseRules(%q): expected %q, got %q", tt.list, tt.expected, names)
+		}
+	}
+
+	for _, mode := range processor.ModeNames {
+		if _, err := processor.New(mode, processor.WithRules([]string{"articles", "case"})); err == nil {
+			t.Errorf("%s: expected New to reject articles before case", mode)
+		}
+	}
+}
+
+func TestSkipRules(t *testing.T) {
+	names, err := processor.SkipRules(processor.RuleNames, []string{"punctuation", " articles"})
+	if expected := []string{"case", "numbers", "quotes", "whitespace"}; err != nil || !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %q, got %q, %v", expected, names, err)
+	}
+	if _, err := processor.SkipRules(processor.RuleNames, []string{"grammar"}); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+}
+
+func TestRuleSelectionAcrossModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []string
+		input    string
+		expected string
+	}{
+		{"markers only", []string{"case", "numbers"}, "a (cap) apple , 1E (hex) !", "A apple , 30 !"},
+		{"numbers only", []string{"numbers"}, "a (cap) apple , 1E (hex) !", "a (cap) apple , 30 !"},
+		{"case only", []string{"case"}, "so (up) 1E (hex) ,", "SO 1E (hex) ,"},
+		{"punctuation only", []string{"punctuation"}, "a apple (up) , ok", "a apple (up), ok"},
+		{"articles without case", []string{"articles", "numbers"}, "a apple and a 1E (hex)", "an apple and a 30"},
+		{"whitespace first", []string{"whitespace", "case"}, "a  b (up)", "a  B"},
+	}
+	for _, tt := range tests {
+		for _, mode := range processor.ModeNames {
+			proc, err := processor.New(mode, processor.WithRules(tt.rules))
+			if err != nil {
+				t.Fatalf("%s in %s mode: %v", tt.name, mode, err)
+			}
+			got := proc.Process(tt.input)
+			if got != tt.expected {
+				t.Errorf("%s in %s mode: expected %q, got %q", tt.name, mode, tt.expected, got)
+			}
+			if again := proc.Process(got); again != got {
+				t.Errorf("%s in %s mode is not idempotent: %q became %q", tt.name, mode, got, again)
+			}
+		}
+	}
+}
+
+func TestConfigRuleOrder(t *testing.T) {
+	_, err := config.Parse("order.yaml", []byte("rules: [articles, case]\n"))
+	var configErr *config.Error
+	if !errors.As(err, &configErr) || err.Error() != `order.yaml:1: rule "articles" must come after "case"` {
+		t.Errorf("Expected a rule order problem, got %v", err)
+	}
+}
+
+func TestCLIRuleFlags(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	input := filepath.Join(dir, "in.txt")
+	output := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(input, []byte("a (cap) apple , 1E (hex) !"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		exitCode int
+		expected string
+	}{
+		{[]string{"--rules", "numbers,case"}, 0, "A apple , 30 !"},
+		{[]string{"--skip-rules", "punctuation,articles"}, 0, "A apple , 30 !"},
+		{[]string{"--rules", "numbers,case,articles", "--skip-rules", "case"}, 0, "a (cap) apple , 30 !"},
+		{[]string{"--rules", "articles,case"}, 1, "Error: rule \"articles\" must come after \"case\"\n"},
+		{[]string{"--skip-rules", "grammar"}, 1, "Error: unknown rule \"grammar\". Use some of [case|numbers|quotes|punctuation|articles|whitespace]\n"},
+	}
+	for _, tt := range tests {
+		os.Remove(output)
+		args := append(append([]string{}, tt.args...), input, output, "fsm")
+		stdout, err := exec.Command(binary, args...).Output()
+		exitCode := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		if exitCode != tt.exitCode {
+			t.Errorf("%q: expected exit code %d, got %d (%v)", tt.args, tt.exitCode, exitCode, err)
+			continue
+		}
+		if tt.exitCode != 0 {
+			if string(stdout) != tt.expected {
+				t.Errorf("%q: expected %q, got %q", tt.args, tt.expected, stdout)
+			}
+			continue
+		}
+		content, err := os.ReadFile(output)
+		if err != nil || string(content) != tt.expected {
+			t.Errorf("%q: expected %q, got %q (%v)", tt.args, tt.expected, content, err)
+		}
+	}
+}
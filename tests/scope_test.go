@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"testing"
+)
+
+func TestMarkerScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    rules.Scope
+		input    string
+		expected string
+	}{
+		{"Unbounded_crosses_lines", rules.ScopeUnbounded, "one two\nthree four (up, 3)", "one TWO\nTHREE FOUR"},
+		{"Line", rules.ScopeLine, "one two\nthree four (up, 3)", "one two\nTHREE FOUR"},
+		{"Line_no_words", rules.ScopeLine, "one two\n(up, 3) three", "one two\n(up, 3) three"},
+		{"Sentence", rules.ScopeSentence, "It ends here. so quiet now (up, 5)", "It ends here. SO QUIET NOW"},
+		{"Sentence_across_lines", rules.ScopeSentence, "Still one\nsentence (cap, 5)", "Still One\nSentence"},
+		{"Sentence_closing_quote", rules.ScopeSentence, "He said 'stop.' and left (up, 4)", "He said 'stop.' AND LEFT"},
+		{"Paragraph", rules.ScopeParagraph, "First part.\n\nSecond. part here (low, 9)", "First part.\n\nsecond. part here"},
+		{"Within_scope", rules.ScopeLine, "so exciting (up, 2)", "SO EXCITING"},
+	}
+
+	for _, tt := range tests {
+		modes := map[string]processor.Processor{
+			"pipeline": processor.NewPipeline(processor.WithScope(tt.scope)),
+			"fsm":      processor.NewFSM(processor.WithScope(tt.scope)),
+			"hybrid":   processor.NewHybrid(processor.WithScope(tt.scope)),
+		}
+		for mode, proc := range modes {
+			t.Run(mode+"_"+tt.name, func(t *testing.T) {
+				result := proc.Process(tt.input)
+				if result != tt.expected {
+					t.Errorf("Scope %s failed:\nInput:    %q\nExpected: %q\nGot:      %q", tt.scope, tt.input, tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestMarkerScopeDiagnostics(t *testing.T) {
+	input := "one two\nthree four (up, 3)\nfive (cap)"
+
+	modes := map[string]processor.DiagnosticProcessor{
+		"pipeline": processor.NewPipeline(processor.WithScope(rules.ScopeLine)),
+		"fsm":      processor.NewFSM(processor.WithScope(rules.ScopeLine)),
+	}
+
+	for mode, proc := range modes {
+		_, diagnostics := proc.ProcessWithDiagnostics(input)
+		if len(diagnostics) != 1 {
+			t.Fatalf("%s: expected 1 diagnostic, got %v", mode, diagnostics)
+		}
+		expected := "2:12: warning: (up, 3) reaches past the start of its line; applied to 2 words"
+		if diagnostics[0].String() != expected {
+			t.Errorf("%s: expected %q, got %q", mode, expected, diagnostics[0].String())
+		}
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	for _, name := range []string{"unbounded", "line", "sentence", "paragraph"} {
+		scope, err := rules.ParseScope(name)
+		if err != nil || scope.String() != name {
+			t.Errorf("ParseScope(%q) = %v, %v", name, scope, err)
+		}
+	}
+	if _, err := rules.ParseScope("page"); err == nil {
+		t.Errorf("Expected error for invalid scope")
+	}
+}
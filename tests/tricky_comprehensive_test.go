@@ -30,7 +30,7 @@ func TestTrickyComprehensive(t *testing.T) {
 		{"Hex_1", "the number 1E (hex) should become 30.", "the number 30 should become 30."},
 		{"Hex_2", "the number 1E (low) (hex) should become 30.", "the number 30 should become 30."},
 		{"Bin_1", "the number 1010 (bin) should become 10.", "the number 10 should become 10."},
-		{"Hex_Invalid", "but 1G (hex) stays (hex) same because invalid.", "but 1G stays same because invalid."},
+		{"Hex_Invalid", "but 1G (hex) stays (hex) same because invalid.", "but 1G (hex) stays (hex) same because invalid."},
 
 		// QUOTES
 		{"Quotes_1", "' this is a quoted text , with punctuation ! '", "'this is a quoted text, with punctuation!'"},
@@ -64,14 +64,14 @@ func TestTrickyComprehensive(t *testing.T) {
 
 		// EDGE CASES
 		{"Edge_1", "a (cap) (up) orange and an (low) (cap) phone. ???", "An orange and An phone.???"},
-		{"Edge_2", "a a a an an a (cap,2) (low,3) orange.", "an a an an an an orange."},
+		{"Edge_2", "a a a an an a (cap,2) (low,3) orange.", "an an an an an an orange."},
 		{"Edge_3", "a , a (cap) orange . a : an (low) apple !", "a, An orange. a: an apple!"},
 
 		// ADVANCED MIXES
 		{"Advanced_1", "Behold 1f4 (hex) warriors and 101001 (bin) enemies marching ... slowly ,but surely !!", "Behold 500 warriors and 41 enemies marching... slowly, but surely!!"},
 		{"Advanced_2", "He whispered ' the END is NEAR ' (low, 3) ,or maybe not (up) ?", "He whispered 'the END is near', or maybe NOT?"},
 		{"Advanced_3", "A hour ago, a elephant walked into a hotel (cap, 5) unexpectedly.", "An hour ago, an Elephant Walked Into A Hotel unexpectedly."},
-		{"Advanced_4", "THIS (low, 4) LINE HAS (up, 2) mixed COMMANDS (low, 5) to test priority.", "THIS (low, 4) line has mixed commands to test priority."},
+		{"Advanced_4", "THIS (low, 4) LINE HAS (up, 2) mixed COMMANDS (low, 5) to test priority.", "this line has mixed commands to test priority."},
 		{"Advanced_5", "Three dots ... or maybe !? punctuation should test spacing ,and emotion !!", "Three dots... or maybe!? punctuation should test spacing, and emotion!!"},
 		{"Advanced_6", "He claimed ' i am invincible (up, 2) and immortal ' (cap, 4) ,yet fell to a arrow.", "He claimed 'i AM INVINCIBLE And Immortal', yet fell to an arrow."},
 		{"Advanced_7", "He claimed'i AM Invincible And Immortal ', yet fell to an arrow.", "He claimed'i AM Invincible And Immortal', yet fell to an arrow."},
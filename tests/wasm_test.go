@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"go-reloaded/internal/processor"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wasmCase is a text the WebAssembly build must process like the native
+// processors
+type wasmCase struct {
+	Text     string            `json:"text"`
+	Mode     string            `json:"mode"`
+	Options  map[string]string `json:"options"`
+	Expected string            `json:"expected"`
+}
+
+// TestWasmUnderNode builds the WebAssembly target and runs its Node harness,
+// checking that it gives the native output in every mode
+func TestWasmUnderNode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping the WebAssembly build in short mode")
+	}
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("Skipping: node is not installed")
+	}
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wasmExec := filepath.Join(strings.TrimSpace(string(goroot)), "lib", "wasm", "wasm_exec.js")
+
+	dir := t.TempDir()
+	wasm := filepath.Join(dir, "go-reloaded.wasm")
+	build := exec.Command("go", "build", "-o", wasm, "../cmd/go-reloaded-wasm")
+	build.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Building the WebAssembly target failed: %v\n%s", err, out)
+	}
+
+	optionSets := []map[string]string{
+		{},
+		{"scope": "paragraph"},
+		{"scope": "line", "whitespace": "collapse"},
+		{"scope": "sentence", "locale": "fr"},
+	}
+	var cases []wasmCase
+	for _, mode := range processor.ModeNames {
+		for _, options := range optionSets {
+			opts, err := processor.ParseOptions(options["scope"], options["whitespace"], options["locale"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			proc, _ := processor.New(mode, opts...)
+			for _, text := range realtimeParityInputs {
+				cases = append(cases, wasmCase{Text: text, Mode: mode, Options: options, Expected: proc.Process(text)})
+			}
+		}
+	}
+	data, err := json.Marshal(cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	casesFile := filepath.Join(dir, "cases.json")
+	if err := os.WriteFile(casesFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := exec.Command(node, "test.js", wasm, wasmExec, casesFile)
+	run.Dir = "../cmd/go-reloaded-wasm"
+	if out, err := run.CombinedOutput(); err != nil {
+		t.Errorf("Node harness failed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/watch"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the watcher while the
+// test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor polls until check passes or a few seconds have gone by
+func waitFor(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if check() {
+			return
+		}
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+// fileIs reports whether the file at path holds content
+func fileIs(path, content string) func() bool {
+	return func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && string(data) == content
+	}
+}
+
+func TestWatch(t *testing.T) {
+	src, out := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("before.txt", "it was a apple (up)")
+	write(".hidden", "a apple")
+
+	var calls atomic.Int32
+	pipeline := processor.NewPipeline()
+	var logs syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.Run(ctx, watch.Config{
+			Src: src,
+			Out: out,
+			Process: func(path, text string) (string, error) {
+				calls.Add(1)
+				if strings.Contains(text, "fail") {
+					return "", errors.New("cannot process")
+				}
+				return pipeline.Process(text), nil
+			},
+			Debounce: 100 * time.Millisecond,
+			Log:      log.New(&logs, "", 0),
+		})
+	}()
+
+	waitFor(t, "the first pass", fileIs(filepath.Join(out, "before.txt"), "it was an APPLE"))
+	if _, err := os.Stat(filepath.Join(out, ".hidden")); !os.IsNotExist(err) {
+		t.Error("Expected hidden files to be left alone")
+	}
+
+	// A burst of writes is processed once
+	start := calls.Load()
+	for i := 0; i < 5; i++ {
+		write("burst.txt", strings.Repeat("go (cap) ", i+1))
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitFor(t, "the burst", fileIs(filepath.Join(out, "burst.txt"), "Go Go Go Go Go "))
+	time.Sleep(250 * time.Millisecond)
+	if n := calls.Load() - start; n != 1 {
+		t.Errorf("Expected the burst to be processed once, got %d", n)
+	}
+
+	// New directories, errors and removed files
+	write("sub/dir/new.txt", "hello , world")
+	waitFor(t, "a file in a new directory", fileIs(filepath.Join(out, "sub", "dir", "new.txt"), "hello, world"))
+	write("bad.txt", "fail")
+	waitFor(t, "the error to be logged", func() bool { return strings.Contains(logs.String(), "error: bad.txt: cannot process") })
+	write("before.txt", "changed (low)")
+	waitFor(t, "the change after an error", fileIs(filepath.Join(out, "before.txt"), "changed"))
+	if err := os.Remove(filepath.Join(src, "before.txt")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the output to be removed", func() bool {
+		_, err := os.Stat(filepath.Join(out, "before.txt"))
+		return os.IsNotExist(err)
+	})
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Run to stop cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop")
+	}
+	if !strings.Contains(logs.String(), "processed sub/dir/new.txt") {
+		t.Errorf("Expected processed files to be logged, got:\n%s", logs.String())
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	dir := t.TempDir()
+	process := func(path, text string) (string, error) { return text, nil }
+	if err := watch.Run(context.Background(), watch.Config{Src: filepath.Join(dir, "missing"), Out: dir, Process: process}); err == nil {
+		t.Error("Expected an error for a missing source directory")
+	}
+	if err := watch.Run(context.Background(), watch.Config{Src: dir, Out: dir, Process: process}); err == nil {
+		t.Error("Expected an error when the output directory is the source directory")
+	}
+}
+
+func TestCLIWatch(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if err := exec.Command("go", "build", "-o", binary, "../cmd/go-reloaded").Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+	src, out := filepath.Join(dir, "src"), filepath.Join(dir, "src", "out")
+	if err := os.Mkdir(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr syncBuffer
+	cmd := exec.Command(binary, "watch", "--mode", "fsm", "--debounce", "20ms", src, out)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	waitFor(t, "the watcher to start", func() bool { return strings.Contains(stderr.String(), "Watching") })
+
+	if err := os.WriteFile(filepath.Join(src, "in.txt"), []byte("so (upp) loud (up)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the output", fileIs(filepath.Join(out, "in.txt"), "so (upp) LOUD"))
+	waitFor(t, "the diagnostic", func() bool { return strings.Contains(stderr.String(), "in.txt:1:4: warning: unknown marker (upp)") })
+
+	cmd.Process.Signal(syscall.SIGINT)
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected watch to exit cleanly on interrupt, got %v\n%s", err, stderr.String())
+	}
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"go-reloaded/internal/processor"
+	"go-reloaded/internal/rules"
+	"testing"
+)
+
+func TestWhitespacePolicy(t *testing.T) {
+	input := "  Indented a (cap) apple ,\tthen\t\ttabs (up)\r\n\r\nnext  paragraph is so exciting (up, 2) !\n    end\n"
+
+	tests := []struct {
+		policy   rules.Whitespace
+		expected string
+	}{
+		{rules.WhitespacePreserve, "  Indented An apple,\tthen\t\tTABS\r\n\r\nnext  paragraph is SO EXCITING!\n    end\n"},
+		{rules.WhitespaceCollapse, "Indented An apple, then TABS\r\n\r\nnext paragraph is SO EXCITING!\nend\n"},
+		{rules.WhitespaceLF, "  Indented An apple,\tthen\t\tTABS\n\nnext  paragraph is SO EXCITING!\n    end\n"},
+		{rules.WhitespaceCRLF, "  Indented An apple,\tthen\t\tTABS\r\n\r\nnext  paragraph is SO EXCITING!\r\n    end\r\n"},
+	}
+
+	for _, tt := range tests {
+		modes := map[string]processor.Processor{
+			"pipeline": processor.NewPipeline(processor.WithWhitespace(tt.policy)),
+			"fsm":      processor.NewFSM(processor.WithWhitespace(tt.policy)),
+			"hybrid":   processor.NewHybrid(processor.WithWhitespace(tt.policy)),
+		}
+		for mode, proc := range modes {
+			t.Run(mode+"_"+tt.policy.String(), func(t *testing.T) {
+				result := proc.Process(input)
+				if result != tt.expected {
+					t.Errorf("%s with %s whitespace:\nExpected: %q\nGot:      %q", mode, tt.policy, tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestMultilineWhitespacePreserved(t *testing.T) {
+	input := "first line\n\tsecond line (up, 2)\n\n  ' quoted '  text\n"
+	expected := "first line\n\tSECOND LINE\n\n  'quoted'  text\n"
+
+	modes := map[string]processor.Processor{
+		"pipeline": processor.NewPipeline(),
+		"fsm":      processor.NewFSM(),
+		"hybrid":   processor.NewHybrid(),
+	}
+	for mode, proc := range modes {
+		if result := proc.Process(input); result != expected {
+			t.Errorf("%s mode:\nExpected: %q\nGot:      %q", mode, expected, result)
+		}
+	}
+}
+
+func TestBackwardMarkerAtParagraphStart(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"hi\n\n(up, 2) there", "HI\n\nthere"},
+		{"hi )\n\n(up) there", "hi )\n\nthere"},
+		{"x '\n\n(up) y", "x '\n\ny"},
+		{"1e\n\n(hex) two", "30\n\ntwo"},
+		{"1e (hex)\n\n(up) two", "30\n\ntwo"},
+		{"one (up:begin) two\n\n(up:end) three", "one TWO\n\nthree"},
+		{"one two\n(up)\nthree", "one TWO\nthree"},
+		{"one two\n(up)\n\nthree", "one TWO\n\nthree"},
+	}
+	for _, mode := range processor.ModeNames {
+		proc, _ := processor.New(mode)
+		for _, tt := range tests {
+			if result := proc.Process(tt.input); result != tt.expected {
+				t.Errorf("%s mode, %q:\nExpected: %q\nGot:      %q", mode, tt.input, tt.expected, result)
+			}
+		}
+	}
+}